@@ -35,7 +35,11 @@ type IPNet struct {
 
 type File struct {
 	Name        *string       `yaml:"name"`
-	Superblock  *IPNet        `yaml:"superBlock"`
+	Superblock  *IPNet        `yaml:"superBlock,omitempty"`
+	Superblocks []*IPNet      `yaml:"superBlocks,omitempty"` // further discontiguous superblocks
+	MaxDepth    int           `yaml:"maxDepth,omitempty"`    // 0 means unlimited nesting
+	Strategy    string        `yaml:"strategy,omitempty"`    // default allocation strategy
+	Provider    string        `yaml:"provider,omitempty"`    // cloud provider reserving addresses in every subnet
 	Allocations []*Allocation `yaml:"allocations"`
 }
 
@@ -49,30 +53,72 @@ type Allocation struct {
 }
 
 type Reference struct {
-	AWS   ReferenceAWS   `yaml:"aws,omitempty"`
-	Azure ReferenceAzure `yaml:"azure,omitempty"`
+	AWS   ReferenceAWS   `yaml:"aws,omitempty" json:"aws"`
+	Azure ReferenceAzure `yaml:"azure,omitempty" json:"azure"`
 
-	DocumentationURI *string `yaml:"documentedAt,omitempty"`
-	Git              *string `yaml:"git,omitempty"`
+	DocumentationURI *string `yaml:"documentedAt,omitempty" json:"documentedAt,omitempty"`
+	Git              *string `yaml:"git,omitempty" json:"git,omitempty"`
 }
 
 type ReferenceAzure struct {
-	Subscription   string `yaml:"subscription,omitempty"`
-	ResourceGroup  string `yaml:"resourceGroup,omitempty"`
-	VirtualNetwork string `yaml:"virtualNetwork,omitempty"`
+	Subscription   string `yaml:"subscription,omitempty" json:"subscription,omitempty"`
+	ResourceGroup  string `yaml:"resourceGroup,omitempty" json:"resourceGroup,omitempty"`
+	VirtualNetwork string `yaml:"virtualNetwork,omitempty" json:"virtualNetwork,omitempty"`
 }
 
 type ReferenceAWS struct {
-	CloudFormationURL string `yaml:"cloudFormationUrl,omitempty"`
+	CloudFormationURL string `yaml:"cloudFormationUrl,omitempty" json:"cloudFormationUrl,omitempty"`
 }
 
+// AllSuperblocks returns superBlock followed by the superBlocks list
+func (f *File) AllSuperblocks() []*IPNet {
+	supers := make([]*IPNet, 0, len(f.Superblocks)+1)
+	if f.Superblock != nil {
+		supers = append(supers, f.Superblock)
+	}
+	return append(supers, f.Superblocks...)
+}
+
+// Validate checks the file structure. MaxDepth limits how deeply suballocations
+// may be nested, 1 allows suballocations but none within those.
 func (f *File) Validate() error {
-	for _, alloc := range f.Allocations {
-		for _, subAllocL1 := range alloc.SubAlloc {
-			if len(subAllocL1.SubAlloc) >= 1 {
-				return errors.Errorf("allocation %s has nested suballocations; this is not supported", alloc.Network.String())
+	if f.MaxDepth < 0 {
+		return errors.Errorf("maxDepth must not be negative, got %d", f.MaxDepth)
+	}
+	if err := validateSuperblocks(f.AllSuperblocks()); err != nil {
+		return err
+	}
+	return validateDepth(f.Allocations, 0, f.MaxDepth)
+}
+
+func validateSuperblocks(supers []*IPNet) error {
+	if len(supers) == 0 {
+		return errors.New("file missing superblock")
+	}
+	for i, super := range supers {
+		if super == nil || super.IPNet == nil {
+			return errors.Errorf("superblock [%d] is empty", i)
+		}
+		for _, other := range supers[:i] {
+			if super.Contains(other.IP) || other.Contains(super.IP) {
+				return &SuperblockError{super, errors.Errorf("superblock %s overlaps with superblock %s", super.String(), other.String())}
 			}
 		}
 	}
 	return nil
 }
+
+func validateDepth(allocations []*Allocation, depth int, maxDepth int) error {
+	for _, alloc := range allocations {
+		if len(alloc.SubAlloc) == 0 {
+			continue
+		}
+		if maxDepth != 0 && depth+1 > maxDepth {
+			return &AllocationError{alloc, errors.Errorf("allocation %s has suballocations nested deeper than the maximum depth of %d", alloc.Network.String(), maxDepth)}
+		}
+		if err := validateDepth(alloc.SubAlloc, depth+1, maxDepth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
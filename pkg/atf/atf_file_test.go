@@ -0,0 +1,82 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package atf
+
+import (
+	"testing"
+)
+
+func nestedFile(depth int, maxDepth int) *File {
+	leaf := &Allocation{Network: &IPNet{CIDR("10.42.0.0/28")}}
+	for i := 0; i < depth; i++ {
+		leaf = &Allocation{Network: &IPNet{CIDR("10.42.0.0/24")}, SubAlloc: []*Allocation{leaf}}
+	}
+	return &File{
+		Superblock:  &IPNet{CIDR("10.42.0.0/16")},
+		MaxDepth:    maxDepth,
+		Allocations: []*Allocation{leaf},
+	}
+}
+
+func TestFile_ValidateDepth(t *testing.T) {
+	tests := []struct {
+		depth    int
+		maxDepth int
+		wantErr  bool
+	}{
+		{depth: 1, maxDepth: 1, wantErr: false},
+		{depth: 2, maxDepth: 1, wantErr: true},
+		{depth: 5, maxDepth: 0, wantErr: false},
+		{depth: 3, maxDepth: 3, wantErr: false},
+		{depth: 0, maxDepth: -1, wantErr: true},
+	}
+	for _, tt := range tests {
+		err := nestedFile(tt.depth, tt.maxDepth).Validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Validate() with depth %d, maxDepth %d: error = %v, wantErr %v", tt.depth, tt.maxDepth, err, tt.wantErr)
+		}
+	}
+}
+
+func TestFile_ValidateSuperblocks(t *testing.T) {
+	tests := []struct {
+		name        string
+		superblock  *IPNet
+		superblocks []*IPNet
+		wantErr     bool
+	}{
+		{"single", &IPNet{CIDR("10.20.0.0/16")}, nil, false},
+		{"list", nil, []*IPNet{{CIDR("10.20.0.0/16")}, {CIDR("172.22.0.0/20")}}, false},
+		{"both", &IPNet{CIDR("10.20.0.0/16")}, []*IPNet{{CIDR("172.22.0.0/20")}}, false},
+		{"none", nil, nil, true},
+		{"overlap", &IPNet{CIDR("10.20.0.0/16")}, []*IPNet{{CIDR("10.20.128.0/17")}}, true},
+		{"empty", nil, []*IPNet{nil}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &File{Superblock: tt.superblock, Superblocks: tt.superblocks}
+			if err := f.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
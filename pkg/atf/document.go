@@ -0,0 +1,512 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package atf
+
+import (
+	"bytes"
+	"log"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Document is an ATF file together with the text it was parsed from. Marshal
+// splices added and removed allocations into that text, so comments, key
+// order and quoting of everything else stay untouched. Changes to fields of
+// allocations that were already in the text are not picked up.
+type Document struct {
+	File *File
+
+	lines       []string
+	root        *yaml.Node
+	allocKey    *yaml.Node
+	allocSeq    *yaml.Node
+	allocations []*allocationNode
+	positions   map[*Allocation]Position
+	// how far sequence items are indented relative to their key
+	seqIndent    int
+	subSeqIndent int
+}
+
+// allocationNode remembers where in the text an allocation was found
+type allocationNode struct {
+	alloc    *Allocation
+	node     *yaml.Node
+	dashCol  int
+	subKey   *yaml.Node
+	subSeq   *yaml.Node
+	children []*allocationNode
+}
+
+var errCannotSplice = errors.New("document layout can't be edited in place")
+
+// ParseDocument reads an ATF file and remembers its layout
+func ParseDocument(data []byte) (*Document, error) {
+	var docNode yaml.Node
+	if err := yaml.Unmarshal(data, &docNode); err != nil {
+		return nil, positionYamlError(err)
+	}
+	file := new(File)
+	doc := &Document{
+		File:      file,
+		lines:     strings.Split(string(data), "\n"),
+		positions: make(map[*Allocation]Position),
+	}
+	if docNode.Kind != yaml.DocumentNode || len(docNode.Content) == 0 {
+		return doc, nil
+	}
+	if err := docNode.Decode(file); err != nil {
+		if _, ok := err.(*PositionError); ok {
+			return nil, err
+		}
+		return nil, positionYamlError(err)
+	}
+	if docNode.Content[0].Kind != yaml.MappingNode {
+		return doc, nil
+	}
+
+	doc.root = docNode.Content[0]
+	doc.allocKey, doc.allocSeq = mappingValue(doc.root, "allocations")
+	doc.recordPositions(file.Allocations, doc.allocSeq)
+	doc.allocations = snapshot(file.Allocations, doc.allocSeq)
+	doc.seqIndent, doc.subSeqIndent = -1, findSubSeqIndent(doc.allocations)
+	if doc.allocKey != nil && len(doc.allocations) > 0 {
+		doc.seqIndent = doc.allocSeq.Column - doc.allocKey.Column
+	}
+	if doc.seqIndent < 0 {
+		doc.seqIndent = doc.subSeqIndent
+	}
+	if doc.subSeqIndent < 0 {
+		doc.subSeqIndent = doc.seqIndent
+	}
+	if doc.seqIndent < 0 {
+		doc.seqIndent, doc.subSeqIndent = 0, 0
+	}
+	return doc, nil
+}
+
+// Position returns where the allocation was found in the source, it is
+// invalid for allocations added after parsing
+func (d *Document) Position(alloc *Allocation) Position {
+	return d.positions[alloc]
+}
+
+// SuperblockPosition returns where the given superblock of the File was found
+// in the source
+func (d *Document) SuperblockPosition(super *IPNet) Position {
+	if d.root == nil || super == nil {
+		return Position{}
+	}
+	if _, value := mappingValue(d.root, "superBlock"); value != nil && d.File.Superblock == super {
+		return nodePosition(value)
+	}
+	_, seq := mappingValue(d.root, "superBlocks")
+	if seq == nil || seq.Kind != yaml.SequenceNode || len(seq.Content) != len(d.File.Superblocks) {
+		return Position{}
+	}
+	for i, other := range d.File.Superblocks {
+		if other == super {
+			return nodePosition(seq.Content[i])
+		}
+	}
+	return Position{}
+}
+
+// KeyPosition returns where the value of the given top-level key was found in
+// the source
+func (d *Document) KeyPosition(key string) Position {
+	if d.root == nil {
+		return Position{}
+	}
+	if _, value := mappingValue(d.root, key); value != nil {
+		return nodePosition(value)
+	}
+	return Position{}
+}
+
+// Locate adds the position of the offending allocation, superblock or key to
+// an AllocationError, SuperblockError or KeyError, other errors are returned
+// as they are
+func (d *Document) Locate(err error) error {
+	var pos Position
+	var allocErr *AllocationError
+	var superErr *SuperblockError
+	var keyErr *KeyError
+	switch {
+	case errors.As(err, &allocErr):
+		pos = d.Position(allocErr.Allocation)
+	case errors.As(err, &superErr):
+		pos = d.SuperblockPosition(superErr.Superblock)
+	case errors.As(err, &keyErr):
+		pos = d.KeyPosition(keyErr.Key)
+	}
+	if pos.IsValid() {
+		return &PositionError{pos, err}
+	}
+	return err
+}
+
+// ValidateRules runs the rules like File.ValidateRules and adds the source
+// position to every finding
+func (d *Document) ValidateRules(rules []*Rule) []*Finding {
+	findings := d.File.ValidateRules(rules)
+	for _, finding := range findings {
+		finding.Position = d.Position(finding.alloc)
+	}
+	return findings
+}
+
+func (d *Document) recordPositions(allocations []*Allocation, seq *yaml.Node) {
+	if seq == nil || seq.Kind != yaml.SequenceNode || len(seq.Content) != len(allocations) {
+		return
+	}
+	for i, alloc := range allocations {
+		node := seq.Content[i]
+		d.positions[alloc] = nodePosition(node)
+		if node.Kind == yaml.MappingNode {
+			_, subSeq := mappingValue(node, "subAlloc")
+			d.recordPositions(alloc.SubAlloc, subSeq)
+		}
+	}
+}
+
+// Marshal returns the text of the document with all allocations added to or
+// removed from the File since parsing. If the original layout can't be edited
+// the whole file is encoded from scratch, which loses comments.
+func (d *Document) Marshal() ([]byte, error) {
+	if d.root != nil {
+		out, err := d.splice()
+		if err == nil {
+			return out, nil
+		}
+		if !errors.Is(err, errCannotSplice) {
+			return nil, err
+		}
+		log.Printf("WARN %s, the file is encoded from scratch and comments are lost", err)
+	}
+	return encodeYaml(d.File)
+}
+
+func encodeYaml(v interface{}) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	enc := yaml.NewEncoder(buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func mappingValue(mapping *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i], mapping.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// snapshot pairs the decoded allocations with their nodes, nil if the
+// sequence isn't a plain block sequence of mappings
+func snapshot(allocations []*Allocation, seq *yaml.Node) []*allocationNode {
+	if seq == nil || seq.Kind != yaml.SequenceNode || seq.Style&yaml.FlowStyle != 0 || len(seq.Content) != len(allocations) {
+		return nil
+	}
+	nodes := make([]*allocationNode, 0, len(allocations))
+	for i, alloc := range allocations {
+		node := seq.Content[i]
+		if node.Kind != yaml.MappingNode || node.Style&yaml.FlowStyle != 0 {
+			return nil
+		}
+		subKey, subSeq := mappingValue(node, "subAlloc")
+		nodes = append(nodes, &allocationNode{
+			alloc:    alloc,
+			node:     node,
+			dashCol:  seq.Column - 1,
+			subKey:   subKey,
+			subSeq:   subSeq,
+			children: snapshot(alloc.SubAlloc, subSeq),
+		})
+	}
+	return nodes
+}
+
+// findSubSeqIndent returns how far the first subAlloc sequence indents its
+// items relative to its key, -1 if there is none
+func findSubSeqIndent(nodes []*allocationNode) int {
+	for _, node := range nodes {
+		if len(node.children) > 0 {
+			return node.subSeq.Column - node.subKey.Column
+		}
+		if indent := findSubSeqIndent(node.children); indent >= 0 {
+			return indent
+		}
+	}
+	return -1
+}
+
+// seqRef describes a sequence of allocations in the text, the key may be
+// missing if no allocations were in it
+type seqRef struct {
+	name        string
+	key         *yaml.Node
+	seq         *yaml.Node
+	indent      int
+	appendAfter int
+	keepEmpty   bool
+	seqIndent   int
+	orig        []*allocationNode
+}
+
+type splicer struct {
+	lines        []string
+	subSeqIndent int
+	deleted      map[int]bool
+	replaced     map[int]string
+	inserted     map[int][]string
+}
+
+func (d *Document) splice() ([]byte, error) {
+	s := &splicer{
+		lines:        d.lines,
+		subSeqIndent: d.subSeqIndent,
+		deleted:      make(map[int]bool),
+		replaced:     make(map[int]string),
+		inserted:     make(map[int][]string),
+	}
+	err := s.splice(seqRef{
+		name:        "allocations",
+		key:         d.allocKey,
+		seq:         d.allocSeq,
+		appendAfter: s.lastContentLine(len(s.lines) - 1),
+		keepEmpty:   true,
+		seqIndent:   d.seqIndent,
+		orig:        d.allocations,
+	}, d.File.Allocations)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]string, 0, len(s.lines))
+	for i, line := range s.lines {
+		if !s.deleted[i] {
+			if replacement, ok := s.replaced[i]; ok {
+				line = replacement
+			}
+			out = append(out, line)
+		}
+		out = append(out, s.inserted[i]...)
+	}
+	return []byte(strings.Join(out, "\n")), nil
+}
+
+func (s *splicer) splice(ref seqRef, current []*Allocation) error {
+	if ref.orig == nil && ref.seq != nil && ref.seq.Kind == yaml.SequenceNode && len(ref.seq.Content) > 0 {
+		// items we couldn't pair with allocations, e.g. flow style
+		return errCannotSplice
+	}
+	origByAlloc := make(map[*Allocation]*allocationNode, len(ref.orig))
+	for _, orig := range ref.orig {
+		origByAlloc[orig.alloc] = orig
+	}
+
+	kept := make(map[*Allocation]bool, len(current))
+	added := make([]*Allocation, 0)
+	for _, alloc := range current {
+		orig, ok := origByAlloc[alloc]
+		if !ok {
+			added = append(added, alloc)
+			continue
+		}
+		if len(added) > 0 {
+			// new allocations are only ever appended
+			return errCannotSplice
+		}
+		kept[alloc] = true
+		if err := s.splice(s.subRef(orig), alloc.SubAlloc); err != nil {
+			return err
+		}
+	}
+
+	lastEnd := -1
+	for _, orig := range ref.orig {
+		start, end := s.itemExtent(orig)
+		if !kept[orig.alloc] {
+			for i := start; i <= end; i++ {
+				s.deleted[i] = true
+			}
+		}
+		lastEnd = end
+	}
+
+	switch {
+	case len(added) > 0 && len(ref.orig) > 0:
+		last := ref.orig[len(ref.orig)-1]
+		gap := last.node.Column - 1 - last.dashCol - 1
+		if gap < 1 {
+			// the dash was on a line of its own
+			gap = 1
+		}
+		return s.insertItems(lastEnd, last.dashCol, gap, added)
+	case len(added) > 0 && ref.key != nil:
+		if ref.seq.Line != ref.key.Line {
+			return errCannotSplice
+		}
+		keyLine := ref.key.Line - 1
+		s.replaced[keyLine] = s.keyLine(ref.key, "")
+		return s.insertItems(keyLine, ref.key.Column-1+ref.seqIndent, 1, added)
+	case len(added) > 0:
+		indent := strings.Repeat(" ", ref.indent)
+		s.inserted[ref.appendAfter] = append(s.inserted[ref.appendAfter], indent+ref.name+":")
+		return s.insertItems(ref.appendAfter, ref.indent+ref.seqIndent, 1, added)
+	case len(kept) == 0 && len(ref.orig) > 0:
+		keyLine := ref.key.Line - 1
+		if ref.keepEmpty {
+			s.replaced[keyLine] = s.keyLine(ref.key, "[]")
+		} else {
+			s.deleted[keyLine] = true
+		}
+	}
+	return nil
+}
+
+func (s *splicer) subRef(orig *allocationNode) seqRef {
+	_, end := s.itemExtent(orig)
+	return seqRef{
+		name:        "subAlloc",
+		key:         orig.subKey,
+		seq:         orig.subSeq,
+		indent:      orig.node.Column - 1,
+		appendAfter: end,
+		seqIndent:   s.subSeqIndent,
+		orig:        orig.children,
+	}
+}
+
+// insertItems renders allocations as sequence items after the given line,
+// with the dash at column dashCol and gap spaces between dash and keys
+func (s *splicer) insertItems(after int, dashCol int, gap int, allocations []*Allocation) error {
+	for _, alloc := range allocations {
+		lines, err := s.itemLines(alloc, dashCol, gap)
+		if err != nil {
+			return err
+		}
+		s.inserted[after] = append(s.inserted[after], lines...)
+	}
+	return nil
+}
+
+// itemLines renders an allocation as a sequence item, suballocations are
+// indented like the existing subAlloc sequences of the document
+func (s *splicer) itemLines(alloc *Allocation, dashCol int, gap int) ([]string, error) {
+	flat := *alloc
+	flat.SubAlloc = nil
+	out, err := encodeYaml(&flat)
+	if err != nil {
+		return nil, err
+	}
+
+	first := strings.Repeat(" ", dashCol) + "-" + strings.Repeat(" ", gap)
+	rest := strings.Repeat(" ", len(first))
+	lines := make([]string, 0)
+	for i, line := range strings.Split(strings.TrimRight(string(out), "\n"), "\n") {
+		if i == 0 {
+			line = first + line
+		} else {
+			line = rest + line
+		}
+		lines = append(lines, line)
+	}
+	if len(alloc.SubAlloc) == 0 {
+		return lines, nil
+	}
+
+	// subAlloc is the last key, like encodeYaml would order it
+	lines = append(lines, rest+"subAlloc:")
+	for _, sub := range alloc.SubAlloc {
+		subLines, err := s.itemLines(sub, len(rest)+s.subSeqIndent, gap)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, subLines...)
+	}
+	return lines, nil
+}
+
+// keyLine returns the line of the key with its value replaced, a trailing
+// comment is kept
+func (s *splicer) keyLine(key *yaml.Node, value string) string {
+	line := s.lines[key.Line-1]
+	colon := key.Column - 1 + strings.Index(line[key.Column-1:], ":")
+	out := line[:colon+1]
+	if value != "" {
+		out += " " + value
+	}
+	if comment := strings.Index(line[colon+1:], "#"); comment >= 0 {
+		out += " " + line[colon+1+comment:]
+	}
+	return out
+}
+
+// itemExtent returns the first and last line of a sequence item, including
+// comments directly above it
+func (s *splicer) itemExtent(orig *allocationNode) (int, int) {
+	start := orig.node.Line - 1
+	// the dash may be on a line of its own
+	for i := start; i >= 0 && i >= start-1; i-- {
+		if indentOf(s.lines[i]) == orig.dashCol && strings.HasPrefix(s.lines[i][orig.dashCol:], "-") {
+			start = i
+			break
+		}
+	}
+	end := start
+	for i := start + 1; i < len(s.lines); i++ {
+		if strings.TrimSpace(s.lines[i]) == "" {
+			continue
+		}
+		if indentOf(s.lines[i]) <= orig.dashCol {
+			break
+		}
+		end = i
+	}
+	for start > 0 && indentOf(s.lines[start-1]) == orig.dashCol && strings.HasPrefix(strings.TrimSpace(s.lines[start-1]), "#") {
+		start--
+	}
+	return start, end
+}
+
+func (s *splicer) lastContentLine(from int) int {
+	for i := from; i > 0; i-- {
+		if strings.TrimSpace(s.lines[i]) != "" {
+			return i
+		}
+	}
+	return 0
+}
+
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
@@ -0,0 +1,197 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package atf
+
+import (
+	"strings"
+	"testing"
+)
+
+const documentText = `# superblock of the test
+superBlock: 10.42.0.0/16
+name: "test" # quoted on purpose
+allocations:
+# the first one
+- cidr: 10.42.0.0/24
+  ident: first
+  subAlloc:
+    - cidr: 10.42.0.0/28
+      ident: sub # keep me
+- cidr: 10.42.1.0/24
+  ident: second
+# trailing comment
+`
+
+func parseTestDocument(t *testing.T, text string) *Document {
+	doc, err := ParseDocument([]byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func expectDocument(t *testing.T, doc *Document, expected string) {
+	out, err := doc.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != expected {
+		t.Fatalf("unexpected document\nexpected:\n%s\nactual:\n%s", expected, out)
+	}
+}
+
+func TestDocument_Unchanged(t *testing.T) {
+	doc := parseTestDocument(t, documentText)
+	expectDocument(t, doc, documentText)
+}
+
+func TestDocument_Append(t *testing.T) {
+	doc := parseTestDocument(t, documentText)
+	first := doc.File.Allocations[0]
+	second := doc.File.Allocations[1]
+	first.SubAlloc = append(first.SubAlloc, &Allocation{Ident: "sub2", Network: &IPNet{CIDR("10.42.0.16/28")}})
+	second.SubAlloc = append(second.SubAlloc, &Allocation{Ident: "sub3", Network: &IPNet{CIDR("10.42.1.0/28")}})
+	doc.File.Allocations = append(doc.File.Allocations, &Allocation{Ident: "third", Network: &IPNet{CIDR("10.42.2.0/24")}})
+
+	expectDocument(t, doc, `# superblock of the test
+superBlock: 10.42.0.0/16
+name: "test" # quoted on purpose
+allocations:
+# the first one
+- cidr: 10.42.0.0/24
+  ident: first
+  subAlloc:
+    - cidr: 10.42.0.0/28
+      ident: sub # keep me
+    - ident: sub2
+      cidr: 10.42.0.16/28
+- cidr: 10.42.1.0/24
+  ident: second
+  subAlloc:
+    - ident: sub3
+      cidr: 10.42.1.0/28
+- ident: third
+  cidr: 10.42.2.0/24
+# trailing comment
+`)
+}
+
+func TestDocument_Remove(t *testing.T) {
+	doc := parseTestDocument(t, documentText)
+	doc.File.Allocations[0].SubAlloc = nil
+	doc.File.Allocations = doc.File.Allocations[:1]
+
+	expectDocument(t, doc, `# superblock of the test
+superBlock: 10.42.0.0/16
+name: "test" # quoted on purpose
+allocations:
+# the first one
+- cidr: 10.42.0.0/24
+  ident: first
+# trailing comment
+`)
+
+	doc.File.Allocations = nil
+	expectDocument(t, doc, `# superblock of the test
+superBlock: 10.42.0.0/16
+name: "test" # quoted on purpose
+allocations: []
+# trailing comment
+`)
+}
+
+func TestDocument_AppendToEmpty(t *testing.T) {
+	doc := parseTestDocument(t, "superBlock: 10.42.0.0/16\nallocations: [] # none yet\n")
+	doc.File.Allocations = append(doc.File.Allocations, &Allocation{Ident: "first", Network: &IPNet{CIDR("10.42.0.0/24")}})
+	expectDocument(t, doc, "superBlock: 10.42.0.0/16\nallocations: # none yet\n- ident: first\n  cidr: 10.42.0.0/24\n")
+
+	doc = parseTestDocument(t, "superBlock: 10.42.0.0/16\n")
+	doc.File.Allocations = append(doc.File.Allocations, &Allocation{Ident: "first", Network: &IPNet{CIDR("10.42.0.0/24")}})
+	expectDocument(t, doc, "superBlock: 10.42.0.0/16\nallocations:\n- ident: first\n  cidr: 10.42.0.0/24\n")
+}
+
+func TestDocument_FlowStyleFallback(t *testing.T) {
+	doc := parseTestDocument(t, "superBlock: 10.42.0.0/16\nallocations: [{cidr: 10.42.0.0/24, ident: first}]\n")
+	doc.File.Allocations = append(doc.File.Allocations, &Allocation{Ident: "second", Network: &IPNet{CIDR("10.42.1.0/24")}})
+	expectDocument(t, doc, `name: null
+superBlock: 10.42.0.0/16
+allocations:
+  - ident: first
+    cidr: 10.42.0.0/24
+  - ident: second
+    cidr: 10.42.1.0/24
+`)
+}
+
+func TestDocument_AppendNested(t *testing.T) {
+	newAlloc := func() *Allocation {
+		return &Allocation{Ident: "zone", Network: &IPNet{CIDR("10.42.2.0/23")}, SubAlloc: []*Allocation{
+			{Ident: "app", Network: &IPNet{CIDR("10.42.2.0/24")}, SubAlloc: []*Allocation{
+				{Ident: "web", Network: &IPNet{CIDR("10.42.2.0/28")}},
+			}},
+		}}
+	}
+
+	// subAlloc items indented by two, like documentText
+	doc := parseTestDocument(t, documentText)
+	doc.File.Allocations = append(doc.File.Allocations, newAlloc())
+	out, err := doc.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `- ident: zone
+  cidr: 10.42.2.0/23
+  subAlloc:
+    - ident: app
+      cidr: 10.42.2.0/24
+      subAlloc:
+        - ident: web
+          cidr: 10.42.2.0/28
+# trailing comment
+`
+	if !strings.HasSuffix(string(out), expected) {
+		t.Errorf("unexpected document\nexpected suffix:\n%s\nactual:\n%s", expected, out)
+	}
+
+	// subAlloc items not indented
+	doc = parseTestDocument(t, `superBlock: 10.42.0.0/16
+allocations:
+- cidr: 10.42.0.0/24
+  subAlloc:
+  - cidr: 10.42.0.0/28
+`)
+	doc.File.Allocations = append(doc.File.Allocations, newAlloc())
+	expectDocument(t, doc, `superBlock: 10.42.0.0/16
+allocations:
+- cidr: 10.42.0.0/24
+  subAlloc:
+  - cidr: 10.42.0.0/28
+- ident: zone
+  cidr: 10.42.2.0/23
+  subAlloc:
+  - ident: app
+    cidr: 10.42.2.0/24
+    subAlloc:
+    - ident: web
+      cidr: 10.42.2.0/28
+`)
+}
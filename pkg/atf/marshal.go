@@ -24,12 +24,22 @@ package atf
 import (
 	"fmt"
 	"net"
+	"strings"
 
 	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
 )
 
 func (ipn *IPNet) MarshalText() (text []byte, err error) {
-	mask, _ := ipn.Mask.Size()
+	mask, bits := ipn.Mask.Size()
+	if bits == 0 {
+		return nil, errors.Errorf("network %s has a non-canonical mask", ipn.IP.String())
+	}
+	// IPv4 networks built in code may carry an IPv6 length mask, IP.String()
+	// prints them as IPv4 so the prefix length has to match
+	if ipn.IP.To4() != nil && bits == 8*net.IPv6len {
+		mask -= 8 * (net.IPv6len - net.IPv4len)
+	}
 	retval := fmt.Sprintf("%s/%d", ipn.IP.String(), mask)
 	return []byte(retval), nil
 }
@@ -39,9 +49,26 @@ func (ipn *IPNet) UnmarshalText(text []byte) error {
 	if err != nil {
 		return err
 	}
+	// IPv4-mapped IPv6 networks can't be told apart from IPv4 networks
+	// once parsed, so they would silently change family on the next marshal
+	if strings.Contains(string(text), ":") && ip.To4() != nil {
+		return errors.Errorf("provided IPv4-mapped IPv6 CIDR '%s', use plain IPv4 notation instead", text)
+	}
 	if !ip.Equal(ipnet.IP) {
 		return errors.Errorf("provided non-net CIDR '%s', did you mean '%s'?", text, ipnet.String())
 	}
 	ipn.IPNet = ipnet
 	return nil
 }
+
+// UnmarshalYAML decodes the network from a YAML scalar, errors carry the
+// position of the node
+func (ipn *IPNet) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.ScalarNode {
+		return &PositionError{nodePosition(value), errors.New("expected a cidr")}
+	}
+	if err := ipn.UnmarshalText([]byte(value.Value)); err != nil {
+		return &PositionError{nodePosition(value), err}
+	}
+	return nil
+}
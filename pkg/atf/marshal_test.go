@@ -26,7 +26,7 @@ import (
 	"reflect"
 	"testing"
 
-	"gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
 )
 
 func CIDR(cidr string) *net.IPNet {
@@ -77,3 +77,47 @@ allocations:
 		t.Fatal("unmarshalled bad data")
 	}
 }
+
+func TestIPNet_MarshalUnmarshalTextIPv6(t *testing.T) {
+	atf := &File{
+		Superblock: &IPNet{CIDR("fd00:1234::/48")},
+		Allocations: []*Allocation{
+			{
+				Network:     &IPNet{CIDR("fd00:1234:0:100::/56")},
+				Description: "a landing zone",
+			},
+		},
+	}
+	bytes, err := yaml.Marshal(&atf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	atf2 := &File{}
+	err = yaml.Unmarshal(bytes, atf2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(atf, atf2) {
+		t.Fatal("deepEqual failed")
+	}
+}
+
+func TestIPNet_MarshalTextIPv4InIPv6Mask(t *testing.T) {
+	ipn := &IPNet{&net.IPNet{IP: net.ParseIP("10.42.0.0"), Mask: net.CIDRMask(112, 128)}}
+	text, err := ipn.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(text) != "10.42.0.0/16" {
+		t.Fatalf("expected 10.42.0.0/16, got %s", text)
+	}
+}
+
+func TestIPNet_UnmarshalTextIPv4Mapped(t *testing.T) {
+	ipn := &IPNet{}
+	if err := ipn.UnmarshalText([]byte("::ffff:10.42.0.0/112")); err == nil {
+		t.Fatal("unmarshalled IPv4-mapped IPv6 network")
+	}
+}
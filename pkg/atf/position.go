@@ -0,0 +1,146 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package atf
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Position is a line and column in the source of a document, both start at 1
+type Position struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// PositionError is an error at a position in the source of a document
+type PositionError struct {
+	Position
+	Err error
+}
+
+func (e *PositionError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Position.String(), e.Err.Error())
+}
+
+func (e *PositionError) Unwrap() error {
+	return e.Err
+}
+
+// PositionErrors are several errors found in one go, e.g. while decoding
+type PositionErrors []*PositionError
+
+func (e PositionErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// AllocationError is an error caused by a specific allocation, Document.Locate
+// turns it into a PositionError
+type AllocationError struct {
+	Allocation *Allocation
+	Err        error
+}
+
+func (e *AllocationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *AllocationError) Unwrap() error {
+	return e.Err
+}
+
+// SuperblockError is an error caused by a specific superblock, Document.Locate
+// turns it into a PositionError
+type SuperblockError struct {
+	Superblock *IPNet
+	Err        error
+}
+
+func (e *SuperblockError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *SuperblockError) Unwrap() error {
+	return e.Err
+}
+
+// KeyError is an error caused by the value of a top-level key of the file,
+// Document.Locate turns it into a PositionError
+type KeyError struct {
+	Key string
+	Err error
+}
+
+func (e *KeyError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *KeyError) Unwrap() error {
+	return e.Err
+}
+
+func nodePosition(node *yaml.Node) Position {
+	return Position{Line: node.Line, Column: node.Column}
+}
+
+var yamlLineError = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// positionYamlError extracts the line numbers yaml puts into its messages,
+// it doesn't report columns so those point to the start of the line
+func positionYamlError(err error) error {
+	var msgs []string
+	if typeErr, ok := err.(*yaml.TypeError); ok {
+		msgs = typeErr.Errors
+	} else {
+		msgs = []string{err.Error()}
+	}
+
+	errs := make(PositionErrors, 0, len(msgs))
+	for _, msg := range msgs {
+		match := yamlLineError.FindStringSubmatch(msg)
+		if match == nil {
+			return err
+		}
+		line, _ := strconv.Atoi(match[1])
+		errs = append(errs, &PositionError{Position{Line: line, Column: 1}, fmt.Errorf("%s", match[2])})
+	}
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return errs
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package atf
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseDocument_ErrorPositions(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected []Position
+	}{
+		{"superBlock: 10.42.0.0/16\nallocations:\n- cidr: 10.42.0.1/24\n", []Position{{3, 9}}},
+		{"superBlock: 10.42.0.0/16\nallocations:\n- cidr: [1]\n", []Position{{3, 9}}},
+		{"superBlock: 10.42.0.0/16\nallocations:\n- reserved: maybe\n  ident: [1]\n", []Position{{3, 1}, {4, 1}}},
+		{"superBlock: 10.42.0.0/16\nallocations: {\n", []Position{{2, 1}}},
+	}
+	for _, tt := range tests {
+		_, err := ParseDocument([]byte(tt.text))
+		got := []Position{}
+		var posErrs PositionErrors
+		var posErr *PositionError
+		switch {
+		case errors.As(err, &posErrs):
+			for _, posErr := range posErrs {
+				got = append(got, posErr.Position)
+			}
+		case errors.As(err, &posErr):
+			got = append(got, posErr.Position)
+		default:
+			t.Fatalf("expected a positioned error for %q, got %v", tt.text, err)
+		}
+		if len(got) != len(tt.expected) {
+			t.Fatalf("expected positions %v for %q, got %v", tt.expected, tt.text, got)
+		}
+		for i := range got {
+			if got[i] != tt.expected[i] {
+				t.Errorf("expected positions %v for %q, got %v", tt.expected, tt.text, got)
+			}
+		}
+	}
+}
+
+func TestDocument_Locate(t *testing.T) {
+	doc := parseTestDocument(t, documentText)
+
+	sub := doc.File.Allocations[0].SubAlloc[0]
+	err := doc.Locate(&AllocationError{Allocation: sub, Err: errors.New("broken")})
+	var posErr *PositionError
+	if !errors.As(err, &posErr) {
+		t.Fatalf("expected a positioned error, got %v", err)
+	}
+	if posErr.Position != (Position{9, 7}) {
+		t.Fatalf("expected position 9:7, got %s", posErr.Position)
+	}
+
+	findings := doc.ValidateRules([]*Rule{})
+	if len(findings) != 0 {
+		t.Fatalf("expected no findings, got %d", len(findings))
+	}
+	doc.File.Allocations[1].Ident = ""
+	findings = doc.ValidateRules(Rules)
+	if len(findings) != 1 || findings[0].Position != (Position{11, 3}) {
+		t.Fatalf("expected one finding at 11:3, got %v", findings)
+	}
+}
+
+func TestDocument_SuperblockPosition(t *testing.T) {
+	doc := parseTestDocument(t, documentText)
+	if pos := doc.SuperblockPosition(doc.File.Superblock); pos != (Position{2, 13}) {
+		t.Fatalf("expected position 2:13, got %s", pos)
+	}
+
+	doc = parseTestDocument(t, "superBlocks:\n- 10.42.0.0/16\n- 172.22.0.0/20\nallocations: []\n")
+	if pos := doc.SuperblockPosition(doc.File.Superblocks[1]); pos != (Position{3, 3}) {
+		t.Fatalf("expected position 3:3, got %s", pos)
+	}
+	if pos := doc.SuperblockPosition(doc.File.Superblock); pos.IsValid() {
+		t.Fatalf("expected no position, got %s", pos)
+	}
+}
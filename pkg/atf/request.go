@@ -0,0 +1,110 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package atf
+
+import (
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Request is an entry of a request manifest, a network to allocate in a
+// batch. Every request has an Ident and either Size or Hosts, Parent and
+// Superblock optionally select where it is allocated.
+type Request struct {
+	Ident       string    `yaml:"ident"`
+	Size        int       `yaml:"size,omitempty"`
+	Hosts       int       `yaml:"hosts,omitempty"`
+	Description string    `yaml:"description,omitempty"`
+	Parent      string    `yaml:"parent,omitempty"`
+	Superblock  string    `yaml:"superblock,omitempty"`
+	IsReserved  bool      `yaml:"reserved,omitempty"`
+	Reference   Reference `yaml:"ref,omitempty"`
+
+	// Position is where the request starts in the manifest
+	Position Position `yaml:"-"`
+}
+
+// Allocation returns the allocation for the request, without network
+func (r *Request) Allocation() *Allocation {
+	return &Allocation{
+		Ident:       r.Ident,
+		IsReserved:  r.IsReserved,
+		Description: r.Description,
+		Reference:   r.Reference,
+	}
+}
+
+// Errorf returns an error at the position of the request
+func (r *Request) Errorf(format string, args ...interface{}) *PositionError {
+	return &PositionError{Position: r.Position, Err: errors.Errorf(format, args...)}
+}
+
+type requestManifest struct {
+	Requests yaml.Node `yaml:"requests"`
+}
+
+// ParseRequests parses a request manifest, a yaml file with a list of
+// requests under the requests key. All invalid requests are reported at once.
+func ParseRequests(data []byte) ([]*Request, error) {
+	var manifest requestManifest
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return nil, positionYamlError(err)
+	}
+	seq := &manifest.Requests
+	if seq.Kind != yaml.SequenceNode || len(seq.Content) == 0 {
+		return nil, &PositionError{Position: Position{Line: 1, Column: 1}, Err: errors.New("manifest has no requests")}
+	}
+
+	requests := make([]*Request, 0, len(seq.Content))
+	errs := make(PositionErrors, 0)
+	idents := make(map[string]bool)
+	for _, node := range seq.Content {
+		request := &Request{Position: Position{Line: node.Line, Column: node.Column}}
+		if err := node.Decode(request); err != nil {
+			return nil, positionYamlError(err)
+		}
+		requests = append(requests, request)
+
+		switch {
+		case request.Size != 0 && request.Hosts != 0:
+			errs = append(errs, request.Errorf("request has both size and hosts"))
+		case request.Size < 0 || request.Hosts < 0:
+			errs = append(errs, request.Errorf("request has a negative size or number of hosts"))
+		case request.Size == 0 && request.Hosts == 0:
+			errs = append(errs, request.Errorf("request needs a size or a number of hosts"))
+		}
+		if request.Parent != "" && request.Superblock != "" {
+			errs = append(errs, request.Errorf("request has both parent and superblock"))
+		}
+		switch {
+		case request.Ident == "":
+			errs = append(errs, request.Errorf("request needs an ident"))
+		case idents[request.Ident]:
+			errs = append(errs, request.Errorf("duplicate ident '%s'", request.Ident))
+		}
+		idents[request.Ident] = true
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return requests, nil
+}
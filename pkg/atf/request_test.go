@@ -0,0 +1,94 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package atf
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseRequests(t *testing.T) {
+	requests, err := ParseRequests([]byte(`requests:
+  - ident: zone
+    size: 20
+    description: landing zone
+  - ident: app
+    hosts: 200
+    parent: zone
+    ref:
+      git: https://example.com/app.git
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(requests))
+	}
+	if requests[0].Size != 20 || requests[0].Description != "landing zone" {
+		t.Errorf("unexpected first request %+v", requests[0])
+	}
+	if requests[1].Hosts != 200 || requests[1].Parent != "zone" || *requests[1].Reference.Git != "https://example.com/app.git" {
+		t.Errorf("unexpected second request %+v", requests[1])
+	}
+	if requests[1].Position != (Position{Line: 5, Column: 5}) {
+		t.Errorf("unexpected position %s", requests[1].Position)
+	}
+	alloc := requests[1].Allocation()
+	if alloc.Ident != "app" || alloc.Network != nil {
+		t.Errorf("unexpected allocation %+v", alloc)
+	}
+}
+
+func TestParseRequests_Invalid(t *testing.T) {
+	_, err := ParseRequests([]byte(`requests:
+  - ident: a
+  - ident: a
+    size: 24
+    hosts: 10
+  - size: 24
+    parent: a
+    superblock: 10.0.0.0/8
+`))
+	var errs PositionErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected position errors, got %v", err)
+	}
+	expected := []string{
+		"2:5: request needs a size or a number of hosts",
+		"3:5: request has both size and hosts",
+		"3:5: duplicate ident 'a'",
+		"6:5: request has both parent and superblock",
+		"6:5: request needs an ident",
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d errors, got %v", len(expected), errs)
+	}
+	for i, err := range errs {
+		if err.Error() != expected[i] {
+			t.Errorf("expected %q, got %q", expected[i], err.Error())
+		}
+	}
+
+	if _, err := ParseRequests([]byte("requests: []\n")); err == nil {
+		t.Error("expected an error for an empty manifest")
+	}
+}
@@ -0,0 +1,297 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package atf
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Finding is a single problem a validation rule found in a file
+type Finding struct {
+	Rule     string   `json:"code"`
+	Severity Severity `json:"severity"`
+	// Path is the YAML path of the offending allocation
+	Path    string `json:"path,omitempty"`
+	Network string `json:"cidr,omitempty"`
+	Ident   string `json:"ident,omitempty"`
+	Message string `json:"message"`
+	// Position is only set for findings of a Document
+	Position Position `json:"position"`
+
+	alloc *Allocation
+}
+
+func (f *Finding) String() string {
+	msg := fmt.Sprintf("%s: %s [%s]", f.Severity, f.Message, f.Rule)
+	subject := f.Path
+	if f.Network != "" {
+		subject = strings.TrimSpace(subject + " (" + f.Network + ")")
+	}
+	if subject != "" {
+		msg = subject + ": " + msg
+	}
+	return msg
+}
+
+// Rule is a single check of the validator
+type Rule struct {
+	Name        string
+	Description string
+	Severity    Severity
+	check       func(f *File, report reportFunc)
+}
+
+type reportFunc func(path string, alloc *Allocation, format string, args ...interface{})
+
+// Rules lists all known validation rules, all of them are enabled by default
+var Rules = []*Rule{
+	{
+		Name:        "missing-cidr",
+		Description: "every allocation needs a cidr",
+		Severity:    SeverityError,
+		check:       checkMissingCidr,
+	},
+	{
+		Name:        "outside-parent",
+		Description: "allocations have to be inside their parent or the superblock",
+		Severity:    SeverityError,
+		check:       checkOutsideParent,
+	},
+	{
+		Name:        "overlap",
+		Description: "allocations next to each other must not overlap",
+		Severity:    SeverityError,
+		check:       checkOverlap,
+	},
+	{
+		Name:        "duplicate-ident",
+		Description: "idents have to be unique in the file",
+		Severity:    SeverityError,
+		check:       checkDuplicateIdent,
+	},
+	{
+		Name:        "empty-ident",
+		Description: "every allocation should have an ident",
+		Severity:    SeverityWarning,
+		check:       checkEmptyIdent,
+	},
+	{
+		Name:        "reserved-description",
+		Description: "reserved allocations should say what they are reserved for",
+		Severity:    SeverityWarning,
+		check:       checkReservedDescription,
+	},
+}
+
+// SelectRules turns rules on and off starting from all rules. "name" or
+// "+name" enables a rule, "-name" disables it, "all" and "none" switch
+// every rule.
+func SelectRules(spec []string) ([]*Rule, error) {
+	enabled := make(map[string]bool, len(Rules))
+	for _, rule := range Rules {
+		enabled[rule.Name] = true
+	}
+	for _, entry := range spec {
+		entry = strings.TrimSpace(entry)
+		switch entry {
+		case "":
+			continue
+		case "all", "none":
+			for name := range enabled {
+				enabled[name] = entry == "all"
+			}
+			continue
+		}
+		on := !strings.HasPrefix(entry, "-")
+		name := strings.TrimLeft(entry, "+-")
+		if _, ok := enabled[name]; !ok {
+			return nil, errors.Errorf("unknown validation rule '%s'", name)
+		}
+		enabled[name] = on
+	}
+
+	selected := make([]*Rule, 0, len(Rules))
+	for _, rule := range Rules {
+		if enabled[rule.Name] {
+			selected = append(selected, rule)
+		}
+	}
+	return selected, nil
+}
+
+// ValidateRules runs the given rules against the file and returns every
+// problem they found, in rule order
+func (f *File) ValidateRules(rules []*Rule) []*Finding {
+	findings := make([]*Finding, 0)
+	for _, rule := range rules {
+		rule.check(f, func(path string, alloc *Allocation, format string, args ...interface{}) {
+			finding := &Finding{
+				Rule:     rule.Name,
+				Severity: rule.Severity,
+				Path:     path,
+				Ident:    alloc.Ident,
+				Message:  fmt.Sprintf(format, args...),
+				alloc:    alloc,
+			}
+			if alloc.Network != nil && alloc.Network.IPNet != nil {
+				finding.Network = alloc.Network.String()
+			}
+			findings = append(findings, finding)
+		})
+	}
+	return findings
+}
+
+// HasErrors reports whether any of the findings is an error
+func HasErrors(findings []*Finding) bool {
+	for _, finding := range findings {
+		if finding.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// walkAllocations calls fn for every allocation with its YAML path and
+// parent, parent is nil for top-level allocations
+func walkAllocations(f *File, fn func(path string, parent *Allocation, alloc *Allocation)) {
+	var walk func(prefix string, parent *Allocation, allocations []*Allocation)
+	walk = func(prefix string, parent *Allocation, allocations []*Allocation) {
+		for i, alloc := range allocations {
+			path := fmt.Sprintf("%s[%d]", prefix, i)
+			fn(path, parent, alloc)
+			walk(path+".subAlloc", alloc, alloc.SubAlloc)
+		}
+	}
+	walk("allocations", nil, f.Allocations)
+}
+
+func hasNetwork(alloc *Allocation) bool {
+	return alloc.Network != nil && alloc.Network.IPNet != nil
+}
+
+func checkMissingCidr(f *File, report reportFunc) {
+	walkAllocations(f, func(path string, parent *Allocation, alloc *Allocation) {
+		if !hasNetwork(alloc) {
+			report(path, alloc, "allocation has no cidr")
+		}
+	})
+}
+
+func checkOutsideParent(f *File, report reportFunc) {
+	walkAllocations(f, func(path string, parent *Allocation, alloc *Allocation) {
+		if !hasNetwork(alloc) {
+			return
+		}
+		if parent != nil {
+			if hasNetwork(parent) && !inside(parent.Network, alloc.Network) {
+				report(path, alloc, "cidr is not inside its parent %s", parent.Network.String())
+			}
+			return
+		}
+
+		names := make([]string, 0)
+		for _, super := range f.AllSuperblocks() {
+			if super == nil || super.IPNet == nil {
+				continue
+			}
+			if inside(super, alloc.Network) {
+				return
+			}
+			names = append(names, super.String())
+		}
+		switch len(names) {
+		case 0:
+		case 1:
+			report(path, alloc, "cidr is not inside its superblock %s", names[0])
+		default:
+			report(path, alloc, "cidr is not inside any superblock (%s)", strings.Join(names, ", "))
+		}
+	})
+}
+
+// inside reports whether inner is a network within outer, smaller than outer
+func inside(outer *IPNet, inner *IPNet) bool {
+	outerSize, _ := outer.Mask.Size()
+	size, _ := inner.Mask.Size()
+	return outer.Contains(inner.IP) && size > outerSize
+}
+
+func checkOverlap(f *File, report reportFunc) {
+	check := func(prefix string, allocations []*Allocation) {
+		for i, alloc := range allocations {
+			for j := 0; j < i; j++ {
+				other := allocations[j]
+				if !hasNetwork(alloc) || !hasNetwork(other) {
+					continue
+				}
+				if alloc.Network.Contains(other.Network.IP) || other.Network.Contains(alloc.Network.IP) {
+					report(fmt.Sprintf("%s[%d]", prefix, i), alloc, "cidr overlaps with %s[%d] (%s)", prefix, j, other.Network.String())
+				}
+			}
+		}
+	}
+	check("allocations", f.Allocations)
+	walkAllocations(f, func(path string, parent *Allocation, alloc *Allocation) {
+		check(path+".subAlloc", alloc.SubAlloc)
+	})
+}
+
+func checkDuplicateIdent(f *File, report reportFunc) {
+	firstPath := make(map[string]string)
+	walkAllocations(f, func(path string, parent *Allocation, alloc *Allocation) {
+		if alloc.Ident == "" {
+			return
+		}
+		if first, ok := firstPath[alloc.Ident]; ok {
+			report(path, alloc, "ident '%s' is already used by %s", alloc.Ident, first)
+			return
+		}
+		firstPath[alloc.Ident] = path
+	})
+}
+
+func checkEmptyIdent(f *File, report reportFunc) {
+	walkAllocations(f, func(path string, parent *Allocation, alloc *Allocation) {
+		if alloc.Ident == "" {
+			report(path, alloc, "allocation has no ident")
+		}
+	})
+}
+
+func checkReservedDescription(f *File, report reportFunc) {
+	walkAllocations(f, func(path string, parent *Allocation, alloc *Allocation) {
+		if alloc.IsReserved && strings.TrimSpace(alloc.Description) == "" {
+			report(path, alloc, "reserved allocation has no description")
+		}
+	})
+}
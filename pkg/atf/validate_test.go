@@ -0,0 +1,121 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package atf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFile_ValidateRules(t *testing.T) {
+	doc, err := ParseDocument([]byte(`superBlock: 10.42.0.0/16
+allocations:
+- cidr: 10.42.0.0/23
+  ident: first
+  subAlloc:
+  - cidr: 10.42.4.0/28
+    ident: sub
+  - cidr: 10.42.0.16/28
+    ident: sub
+  - cidr: 10.42.0.16/29
+    reserved: true
+- cidr: 10.42.1.0/24
+  ident: second
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	findings := doc.File.ValidateRules(Rules)
+	got := make([]string, 0, len(findings))
+	for _, finding := range findings {
+		got = append(got, finding.Rule+" "+finding.Path)
+	}
+	expected := []string{
+		"outside-parent allocations[0].subAlloc[0]",
+		"overlap allocations[1]",
+		"overlap allocations[0].subAlloc[2]",
+		"duplicate-ident allocations[0].subAlloc[1]",
+		"empty-ident allocations[0].subAlloc[2]",
+		"reserved-description allocations[0].subAlloc[2]",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected findings %v, got %v", expected, got)
+	}
+	if !HasErrors(findings) {
+		t.Fatal("findings should contain errors")
+	}
+}
+
+func TestFile_ValidateRulesSuperblocks(t *testing.T) {
+	doc, err := ParseDocument([]byte(`superBlocks:
+- 10.20.0.0/16
+- 172.22.0.0/20
+allocations:
+- cidr: 10.20.0.0/24
+  ident: first
+- cidr: 172.22.1.0/24
+  ident: second
+- cidr: 192.168.0.0/24
+  ident: third
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	findings := doc.ValidateRules(Rules)
+	if len(findings) != 1 || findings[0].Path != "allocations[2]" || findings[0].Rule != "outside-parent" {
+		t.Fatalf("expected allocations[2] outside of the superblocks, got %v", findings)
+	}
+	if findings[0].Position != (Position{9, 3}) {
+		t.Fatalf("expected position 9:3, got %s", findings[0].Position)
+	}
+}
+
+func TestSelectRules(t *testing.T) {
+	tests := []struct {
+		spec     []string
+		expected []string
+		wantErr  bool
+	}{
+		{spec: nil, expected: []string{"missing-cidr", "outside-parent", "overlap", "duplicate-ident", "empty-ident", "reserved-description"}},
+		{spec: []string{"-empty-ident", "-reserved-description"}, expected: []string{"missing-cidr", "outside-parent", "overlap", "duplicate-ident"}},
+		{spec: []string{"none", "+overlap", "duplicate-ident"}, expected: []string{"overlap", "duplicate-ident"}},
+		{spec: []string{"no-such-rule"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		rules, err := SelectRules(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("SelectRules(%v) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+		}
+		if tt.wantErr {
+			continue
+		}
+		got := make([]string, 0, len(rules))
+		for _, rule := range rules {
+			got = append(got, rule.Name)
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("SelectRules(%v) = %v, expected %v", tt.spec, got, tt.expected)
+		}
+	}
+}
@@ -23,10 +23,14 @@ package atfutil
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/pkg/errors"
 
@@ -37,8 +41,6 @@ import (
 
 	"atfutil/pkg/atf"
 
-	"github.com/go-yaml/yaml"
-
 	"github.com/spf13/cobra"
 )
 
@@ -47,11 +49,57 @@ var rootCmd = &cobra.Command{
 	Short: "atfutil can validate and render atf (allocation table format) yaml files",
 }
 
+// quitWithError prints the error and exits, errors with a position in the
+// input file are printed as file:line:column: message
 func quitWithError(err error) {
-	fmt.Fprintf(os.Stderr, "err: %s\n", err.Error())
+	name := inputName()
+	var fileErr *fileError
+	if errors.As(err, &fileErr) {
+		name = fileErr.name
+	}
+
+	var posErrs atf.PositionErrors
+	var posErr *atf.PositionError
+	switch {
+	case errors.As(err, &posErrs):
+		for _, posErr := range posErrs {
+			fmt.Fprintf(os.Stderr, "%s:%s\n", name, posErr.Error())
+		}
+	case errors.As(err, &posErr):
+		fmt.Fprintf(os.Stderr, "%s:%s\n", name, posErr.Error())
+	default:
+		fmt.Fprintf(os.Stderr, "err: %s\n", err.Error())
+	}
 	os.Exit(1)
 }
 
+// fileError is an error in a file other than the input file
+type fileError struct {
+	name string
+	err  error
+}
+
+func (e *fileError) Error() string {
+	return e.name + ": " + e.err.Error()
+}
+
+func (e *fileError) Unwrap() error {
+	return e.err
+}
+
+// inputName is the input file name as shown in messages
+func inputName() string {
+	return displayName(*inputFilename)
+}
+
+// displayName is a file name as shown in messages
+func displayName(name string) string {
+	if name == "-" {
+		return "<stdin>"
+	}
+	return name
+}
+
 func getInputFile(inputFilename string) (*os.File, error) {
 	var inputFile *os.File
 	if inputFilename == "" {
@@ -86,76 +134,300 @@ func getOutputFile(outputFilename string) (*os.File, error) {
 	return outputFile, nil
 }
 
-func loadAtfFromFile(inputFile *os.File) (*atf.File, error) {
+// loadAtfDocument loads an atf file keeping its layout
+func loadAtfDocument(inputFile *os.File) (*atf.Document, error) {
 	data, err := ioutil.ReadAll(inputFile)
 	if err != nil {
 		return nil, err
 	}
-	atf := new(atf.File)
-	err = yaml.Unmarshal(data, atf)
+	doc, err := atf.ParseDocument(data)
 	if err != nil {
 		return nil, err
 	}
-	if atf.Superblock == nil {
-		return nil, errors.New("file missing superblock")
+	if len(doc.File.AllSuperblocks()) == 0 {
+		return nil, &atf.PositionError{Position: atf.Position{Line: 1, Column: 1}, Err: errors.New("file missing superblock")}
 	}
-	for i, alloc := range atf.Allocations {
+	for i, alloc := range doc.File.Allocations {
 		if alloc.Network == nil {
-			return nil, errors.Errorf("file missing network in allocation [%d]", i)
+			return nil, doc.Locate(&atf.AllocationError{Allocation: alloc, Err: errors.Errorf("file missing network in allocation [%d]", i)})
 		}
 	}
-	return atf, nil
+	return doc, nil
+}
+
+// loadInput loads the input file and builds its pools, errors caused by an
+// allocation carry its position
+func loadInput() (*atf.Document, *netpool.ParsedATF, error) {
+	return loadFile(*inputFilename)
+}
+
+// loadFile loads the given file and builds its pools
+func loadFile(name string) (*atf.Document, *netpool.ParsedATF, error) {
+	inFile, err := getInputFile(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer inFile.Close()
+
+	doc, err := loadAtfDocument(inFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	pool, err := netpool.FromAtf(doc.File)
+	if err != nil {
+		return nil, nil, doc.Locate(err)
+	}
+	return doc, pool, nil
+}
+
+func checkInPlace() error {
+	// output filename is set and in-place is set,
+	if *outputFilename != "-" && *inPlace {
+		return errors.New("cannot use --output-file and --in-place at the same time")
+	}
+	return nil
+}
+
+// writeAtfFile writes the modified document to the output file, or back to
+// the input file when --in-place is set
+func writeAtfFile(doc *atf.Document) error {
+	outBytes, err := doc.Marshal()
+	if err != nil {
+		return err
+	}
+
+	// output filename is not set and in-place is set
+	outName := *outputFilename
+	if outName == "-" && *inPlace {
+		outName = *inputFilename
+	}
+
+	outFile, err := getOutputFile(outName)
+	if err != nil {
+		return err
+	}
+	defer outFile.Close()
+	_, err = outFile.Write(outBytes)
+	return err
 }
 
 var validateCmd = &cobra.Command{
-	Use:   "validate",
+	Use:   "validate [file|directory]",
 	Short: "validate an input file to be valid atf and have no network overlap",
+	Long:  "validate an input file to be valid atf and have no network overlap, all problems found by the validation rules are reported at once. Given a directory every atf file in it is validated and superblocks of different files must not overlap.",
+	Args:  cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		inFile, err := getInputFile(*inputFilename)
+		rules, err := atf.SelectRules(*validateRules)
 		if err != nil {
 			quitWithError(err)
 		}
-		defer inFile.Close()
-		atf, err := loadAtfFromFile(inFile)
-		if err != nil {
-			quitWithError(err)
+		if *validateOutput != "text" && *validateOutput != "json" {
+			quitWithError(errors.Errorf("unknown output format '%s'", *validateOutput))
+		}
+
+		name := *inputFilename
+		if len(args) == 1 {
+			if cmd.Flags().Changed("input-file") {
+				quitWithError(errors.New("cannot use --input-file and a file or directory argument at the same time"))
+			}
+			name = args[0]
+		}
+		var results []*validateResult
+		workspace := isDir(name)
+		if workspace {
+			results, err = validateWorkspace(name, rules)
+		} else {
+			var findings []*atf.Finding
+			findings, err = validateFile(name, rules)
+			results = []*validateResult{newValidateResult(name, findings)}
 		}
-		_, err = netpool.FromAtf(atf)
 		if err != nil {
 			quitWithError(err)
 		}
+
+		if *validateOutput == "json" {
+			outFile, err := getOutputFile(*outputFilename)
+			if err != nil {
+				quitWithError(err)
+			}
+			defer outFile.Close()
+			enc := json.NewEncoder(outFile)
+			enc.SetIndent("", "  ")
+			if workspace {
+				err = enc.Encode(results)
+			} else {
+				err = enc.Encode(results[0])
+			}
+			if err != nil {
+				quitWithError(err)
+			}
+		} else {
+			for _, result := range results {
+				for _, finding := range result.Findings {
+					if finding.Position.IsValid() {
+						fmt.Fprintf(os.Stderr, "%s:%s: %s\n", result.File, finding.Position, finding.String())
+					} else {
+						fmt.Fprintf(os.Stderr, "%s: %s\n", result.File, finding.String())
+					}
+				}
+			}
+		}
+
+		for _, result := range results {
+			if !result.Valid {
+				os.Exit(1)
+			}
+		}
 		os.Exit(0)
 	},
 }
 
+type validateResult struct {
+	File     string         `json:"file"`
+	Valid    bool           `json:"valid"`
+	Findings []*atf.Finding `json:"findings"`
+}
+
+func newValidateResult(name string, findings []*atf.Finding) *validateResult {
+	return &validateResult{
+		File:     displayName(name),
+		Valid:    !atf.HasErrors(findings),
+		Findings: findings,
+	}
+}
+
+// validateFile runs the rules against the given file, a file that can't be
+// loaded is reported as findings as well
+func validateFile(name string, rules []*atf.Rule) ([]*atf.Finding, error) {
+	inFile, err := getInputFile(name)
+	if err != nil {
+		return nil, err
+	}
+	defer inFile.Close()
+
+	doc, err := loadAtfDocument(inFile)
+	if err != nil {
+		return errorFindings("load", err), nil
+	}
+	return validateDocument(doc, rules), nil
+}
+
+// validateDocument runs the rules against a document, if they find no errors
+// the pools are built as well
+func validateDocument(doc *atf.Document, rules []*atf.Rule) []*atf.Finding {
+	findings := doc.ValidateRules(rules)
+	if atf.HasErrors(findings) {
+		return findings
+	}
+	_, err := netpool.FromAtf(doc.File)
+	if err != nil {
+		findings = append(findings, errorFindings("pool", doc.Locate(err))...)
+	}
+	return findings
+}
+
+// errorFindings turns an error into findings with the given code
+func errorFindings(code string, err error) []*atf.Finding {
+	var posErrs atf.PositionErrors
+	if errors.As(err, &posErrs) {
+		findings := make([]*atf.Finding, 0, len(posErrs))
+		for _, posErr := range posErrs {
+			findings = append(findings, errorFindings(code, posErr)...)
+		}
+		return findings
+	}
+
+	finding := &atf.Finding{
+		Rule:     code,
+		Severity: atf.SeverityError,
+		Message:  err.Error(),
+	}
+	var posErr *atf.PositionError
+	if errors.As(err, &posErr) {
+		finding.Position = posErr.Position
+		finding.Message = posErr.Err.Error()
+	}
+	var allocErr *atf.AllocationError
+	if errors.As(err, &allocErr) && allocErr.Allocation.Network != nil {
+		finding.Network = allocErr.Allocation.Network.String()
+		finding.Ident = allocErr.Allocation.Ident
+	}
+	return []*atf.Finding{finding}
+}
+
+// renderTemplate renders with the template file given by --template
+func renderTemplate(target io.Writer, pool *netpool.ParsedATF, includeFree bool) error {
+	if *renderTemplateFile == "" {
+		return errors.New("render format template needs a --template file")
+	}
+	text, err := ioutil.ReadFile(*renderTemplateFile)
+	if err != nil {
+		return err
+	}
+	return render.RenderPoolToTemplate(target, pool, filepath.Base(*renderTemplateFile), string(text), includeFree)
+}
+
+// renderPool renders in the format given by --render-format
+func renderPool(target io.Writer, pool *netpool.ParsedATF, includeFree bool) error {
+	switch *renderFormat {
+	case "markdown":
+		dmr, err := render.NewMarkdownRenderer(target, *renderColumns)
+		if err != nil {
+			return err
+		}
+		free := render.FreeModeFor(includeFree)
+		if *renderCollapseFree {
+			free = render.FreeRanges
+		}
+		render.RenderPoolToMarkdownWith(target, pool, free, dmr)
+		return nil
+	case "json":
+		return render.RenderPoolToJSON(target, pool, includeFree)
+	case "csv":
+		return render.RenderPoolToCSV(target, pool, includeFree, ',')
+	case "tsv":
+		return render.RenderPoolToCSV(target, pool, includeFree, '\t')
+	case "html":
+		return render.RenderPoolToHTML(target, pool, includeFree)
+	case "svg":
+		return render.RenderPoolToSVG(target, pool)
+	case "template":
+		return renderTemplate(target, pool, includeFree)
+	default:
+		return errors.Errorf("unknown render format '%s'", *renderFormat)
+	}
+}
+
 var renderCmd = &cobra.Command{
 	Use:   "render",
 	Short: "render an atf.yaml to a human readable format",
 	Run: func(cmd *cobra.Command, args []string) {
+		// json is meant for further processing and always lists free space
+		includeFree := *renderFree || *renderFormat == "json"
+
+		if *renderDir != "" {
+			if *renderOut == "" {
+				quitWithError(errors.New("rendering a directory needs --out"))
+			}
+			err := renderWorkspace(*renderDir, *renderOut, includeFree)
+			if err != nil {
+				quitWithError(err)
+			}
+			os.Exit(0)
+		}
+
 		outBuffer := &bytes.Buffer{}
 
-		inFile, err := getInputFile(*inputFilename)
+		_, pool, err := loadInput()
 		if err != nil {
 			quitWithError(err)
 		}
-		defer inFile.Close()
 
-		atfFile, err := loadAtfFromFile(inFile)
-		if err != nil {
-			quitWithError(err)
-		}
-		pool, err := netpool.FromAtf(atfFile)
+		err = renderPool(outBuffer, pool, includeFree)
 		if err != nil {
 			quitWithError(err)
 		}
 
-		switch *renderFormat {
-		case "markdown":
-			render.RenderPoolToMarkdown(outBuffer, pool, *renderFree)
-		default:
-			quitWithError(errors.New("unknown render format"))
-		}
-
 		outFile, err := getOutputFile(*outputFilename)
 		if err != nil {
 			quitWithError(err)
@@ -173,67 +445,288 @@ var renderCmd = &cobra.Command{
 var allocCmd = &cobra.Command{
 	Use:   "alloc",
 	Short: "allocate a new subnet",
-	Long:  "allocate a new subnet, the smallest fitting free slice is automatically found and allocated to keep your IP space fragmentation low",
+	Long:  "allocate a new subnet, by default the smallest fitting free slice is automatically found and allocated to keep your IP space fragmentation low. With --from all requests of a manifest are allocated at once.",
 	Run: func(cmd *cobra.Command, args []string) {
-		// output filename is set and in-place is set,
-		if *outputFilename != "-" && *inPlace {
-			quitWithError(errors.New("cannot use --output-file and --in-place at the same time"))
+		err := checkInPlace()
+		if err != nil {
+			quitWithError(err)
 		}
 
-		inFile, err := getInputFile(*inputFilename)
+		doc, pool, err := loadInput()
 		if err != nil {
 			quitWithError(err)
 		}
-		defer inFile.Close()
+		atfFile := doc.File
+
+		if *allocFrom != "" {
+			if err := allocBatch(cmd, doc, pool, *allocFrom); err != nil {
+				quitWithError(err)
+			}
+			if err := writeAtfFile(doc); err != nil {
+				quitWithError(err)
+			}
+			os.Exit(0)
+		}
 
-		atfFile, err := loadAtfFromFile(inFile)
-		if err != nil {
+		if *allocProvider != "" && *allocHosts == -1 {
+			quitWithError(errors.New("--provider is only used with --hosts"))
+		}
+		if err := checkIdent(pool, *allocIdent); err != nil {
 			quitWithError(err)
 		}
-		pool, err := netpool.FromAtf(atfFile)
+
+		var parentAlloc *atf.Allocation
+		if *allocParent != "" {
+			parentAlloc = pool.FindAllocation(*allocParent)
+			if parentAlloc == nil {
+				quitWithError(errors.Errorf("no allocation matches parent '%s'", *allocParent))
+			}
+		}
+		targetPools, err := allocPools(pool, parentAlloc, *allocSuperblock)
 		if err != nil {
 			quitWithError(err)
 		}
 
-		superAllocSize, _ := atfFile.Superblock.Mask.Size()
+		var net *atf.IPNet
+		if *allocCidr != "" {
+			if *allocSize != -1 || *allocHosts != -1 {
+				quitWithError(errors.New("cannot use --size or --hosts and --cidr at the same time"))
+			}
+			if *allocStrategy != "" {
+				quitWithError(errors.New("cannot use --strategy and --cidr at the same time"))
+			}
+			net, err = claimNetwork(pool, targetPools, *allocCidr)
+			if err != nil {
+				quitWithError(err)
+			}
+		} else {
+			strategy := pool.Strategy
+			if *allocStrategy != "" {
+				strategy, err = netcalc.StrategyByName(*allocStrategy)
+				if err != nil {
+					quitWithError(err)
+				}
+			}
+
+			size := *allocSize
+			if *allocHosts != -1 {
+				if *allocSize != -1 {
+					quitWithError(errors.New("cannot use --size and --hosts at the same time"))
+				}
+				provider := pool.Provider
+				if *allocProvider != "" {
+					provider, err = netcalc.ProviderByName(*allocProvider)
+					if err != nil {
+						quitWithError(err)
+					}
+				}
+				family, err := poolsFamily(targetPools)
+				if err == nil {
+					size, err = hostsPrefixLen(family, *allocHosts, provider)
+				}
+				if err != nil {
+					quitWithError(err)
+				}
+			}
+
+			ipnet, err := allocSized(targetPools, size, strategy)
+			if err != nil {
+				quitWithError(err)
+			}
+			net = &atf.IPNet{IPNet: ipnet}
+		}
 
-		if *allocSize > netcalc.AWS_MIN_SUBNET_SIZE || *allocSize <= superAllocSize {
-			quitWithError(errors.Errorf("requested block size is out of range (%d < block < %d)", superAllocSize, netcalc.AWS_MIN_SUBNET_SIZE))
+		newAlloc := newAllocation(net)
+		if parentAlloc != nil {
+			parentAlloc.SubAlloc = append(parentAlloc.SubAlloc, newAlloc)
+		} else {
+			atfFile.Allocations = append(atfFile.Allocations, newAlloc)
+		}
+		if err := atfFile.Validate(); err != nil {
+			quitWithError(err)
 		}
 
-		// TODO: allow allocating from a suballocation with a flag
-		net, err := pool.Pool.Alloc(*allocSize)
+		err = writeAtfFile(doc)
 		if err != nil {
 			quitWithError(err)
 		}
 
-		atfFile.Allocations = append(atfFile.Allocations, &atf.Allocation{
-			Network:     &atf.IPNet{IPNet: net},
-			Description: *allocDesc,
-		})
+		os.Exit(0)
+	},
+}
+
+// checkIdent refuses an ident that is already used in the file, allocations
+// without ident are always accepted
+func checkIdent(parsed *netpool.ParsedATF, ident string) error {
+	if ident != "" && parsed.GetAtfAllocationByIdent(ident) != nil {
+		return errors.Errorf("ident '%s' is already in use", ident)
+	}
+	return nil
+}
+
+// newAllocation builds the allocation of the network from the alloc flags
+func newAllocation(network *atf.IPNet) *atf.Allocation {
+	return &atf.Allocation{
+		Ident:       *allocIdent,
+		IsReserved:  *allocReserved,
+		Network:     network,
+		Description: *allocDesc,
+		Reference: atf.Reference{
+			AWS: atf.ReferenceAWS{
+				CloudFormationURL: *allocAWSCloudFormationURL,
+			},
+			Azure: atf.ReferenceAzure{
+				Subscription:   *allocAzureSubscription,
+				ResourceGroup:  *allocAzureResourceGroup,
+				VirtualNetwork: *allocAzureVirtualNetwork,
+			},
+			DocumentationURI: optionalString(*allocDocumentationURI),
+			Git:              optionalString(*allocGit),
+		},
+	}
+}
 
-		outBytes, err := yaml.Marshal(atfFile)
+// allocPools returns the pools to allocate from: the pool of the parent
+// allocation, the superblock selected by cidr or all superblocks
+func allocPools(parsed *netpool.ParsedATF, parentAlloc *atf.Allocation, superblock string) ([]*netcalc.IPNetPool, error) {
+	if parentAlloc != nil {
+		if superblock != "" {
+			return nil, errors.New("cannot use --parent and --superblock at the same time")
+		}
+		pool, err := parsed.GetOrCreatePoolByNet(parentAlloc.Network.String())
 		if err != nil {
-			quitWithError(err)
+			return nil, err
+		}
+		return []*netcalc.IPNetPool{pool}, nil
+	}
+	if superblock == "" {
+		return parsed.Pools, nil
+	}
+	for _, pool := range parsed.Pools {
+		if pool.Super().String() == superblock {
+			return []*netcalc.IPNetPool{pool}, nil
+		}
+	}
+	return nil, errors.Errorf("no superblock matches '%s'", superblock)
+}
+
+// allocSized allocates a network of the given prefix length from whichever
+// of the pools the strategy picks, pools too small for the size are skipped
+func allocSized(pools []*netcalc.IPNetPool, size int, strategy netcalc.Strategy) (*net.IPNet, error) {
+	candidates := make([]*netcalc.IPNetPool, 0, len(pools))
+	for _, pool := range pools {
+		superAllocSize, _ := pool.Super().Mask.Size()
+		if size > superAllocSize && size <= netcalc.MinSubnetSize(pool.Super()) {
+			candidates = append(candidates, pool)
 		}
+	}
+	switch {
+	case len(candidates) == 0 && len(pools) == 1:
+		superAllocSize, _ := pools[0].Super().Mask.Size()
+		return nil, errors.Errorf("requested block size is out of range (%d < block <= %d)", superAllocSize, netcalc.MinSubnetSize(pools[0].Super()))
+	case len(candidates) == 0:
+		return nil, errors.Errorf("requested block size /%d is out of range for every superblock", size)
+	}
+	for _, pool := range candidates[1:] {
+		if !netcalc.SameFamily(pool.Super(), candidates[0].Super()) {
+			return nil, errors.Errorf("/%d fits IPv4 and IPv6 superblocks, select one with --superblock", size)
+		}
+	}
 
-		// output filename is not set and in-place is set
-		if *outputFilename == "-" && *inPlace {
-			*outputFilename = *inputFilename
+	_, ipnet, err := netcalc.AllocFrom(candidates, size, strategy)
+	return ipnet, err
+}
+
+// poolsFamily returns the superblock of the first pool, as long as all pools
+// are of the same address family
+func poolsFamily(pools []*netcalc.IPNetPool) (*net.IPNet, error) {
+	super := pools[0].Super()
+	for _, pool := range pools[1:] {
+		if !netcalc.SameFamily(pool.Super(), super) {
+			return nil, errors.New("the file has IPv4 and IPv6 superblocks, select a superblock to allocate by hosts")
 		}
+	}
+	return super, nil
+}
 
-		outFile, err := getOutputFile(*outputFilename)
-		if err != nil {
-			quitWithError(err)
+// hostsPrefixLen returns the prefix length of the smallest network in the
+// address family of family with the given number of usable hosts
+func hostsPrefixLen(family *net.IPNet, hosts int, provider netcalc.Provider) (int, error) {
+	if hosts < 1 {
+		return 0, errors.Errorf("invalid number of hosts %d", hosts)
+	}
+	size := netcalc.PrefixLenForHosts(hosts, netcalc.MaskBits(family), provider)
+	if size < 0 {
+		return 0, errors.Errorf("no network has %d usable hosts", hosts)
+	}
+	// providers don't accept smaller subnets
+	if minSubnetSize := netcalc.MinSubnetSize(family); size > minSubnetSize {
+		size = minSubnetSize
+	}
+	return size, nil
+}
+
+// claimNetwork claims exactly the given cidr in whichever of the pools
+// contains it, overlaps are reported with the ident of the conflicting
+// allocation
+func claimNetwork(parsed *netpool.ParsedATF, pools []*netcalc.IPNetPool, cidr string) (*atf.IPNet, error) {
+	claimed := &atf.IPNet{}
+	if err := claimed.UnmarshalText([]byte(cidr)); err != nil {
+		return nil, err
+	}
+	// a single pool reports out of bounds networks itself
+	targetPool := pools[0]
+	if len(pools) > 1 {
+		targetPool = nil
+		for _, pool := range pools {
+			if netcalc.SameFamily(pool.Super(), claimed.IPNet) && pool.Super().Contains(claimed.IP) {
+				targetPool = pool
+				break
+			}
 		}
-		defer outFile.Close()
-		outFile.Write(outBytes)
-		if err != nil {
-			quitWithError(err)
+		if targetPool == nil {
+			return nil, errors.Errorf("%s is not inside any superblock", cidr)
+		}
+	}
+	err := targetPool.Claim(claimed.IPNet)
+	var overlapErr *netcalc.OverlapError
+	if errors.As(err, &overlapErr) {
+		conflictNet := overlapErr.Conflict.String()
+		conflict := parsed.GetAtfAllocationByNet(conflictNet)
+		ident := "without ident"
+		if conflict != nil && conflict.Ident != "" {
+			ident = fmt.Sprintf("'%s'", conflict.Ident)
+		}
+		conflictSize, _ := overlapErr.Conflict.Mask.Size()
+		claimedSize, _ := claimed.Mask.Size()
+		if claimedSize == conflictSize {
+			return nil, errors.Errorf("%s is already allocated (%s)", cidr, ident)
 		}
+		if claimedSize > conflictSize {
+			return nil, errors.Errorf("%s is inside allocation %s (%s), use --parent to claim it there", cidr, conflictNet, ident)
+		}
+		return nil, errors.Errorf("%s overlaps with allocation %s (%s)", cidr, conflictNet, ident)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return claimed, nil
+}
 
-		os.Exit(0)
-	},
+func ruleNames() string {
+	names := make([]string, 0, len(atf.Rules))
+	for _, rule := range atf.Rules {
+		names = append(names, rule.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// optionalString maps an unset string flag to a nil pointer
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
 }
 
 func Command() *cobra.Command {
@@ -242,13 +735,36 @@ func Command() *cobra.Command {
 
 var inputFilename *string
 var outputFilename *string
-var inPlace *bool
+var inPlace = new(bool)
+
+var validateRules *[]string
+var validateOutput *string
 
 var renderFree *bool
-var renderFormat *string
+var renderFormat = new(string)
+var renderTemplateFile = new(string)
+var renderColumns = new([]string)
+var renderCollapseFree *bool
+var renderDir *string
+var renderOut *string
 
 var allocSize *int
 var allocDesc *string
+var allocParent *string
+var allocCidr *string
+var allocStrategy *string
+var allocSuperblock *string
+var allocHosts *int
+var allocProvider *string
+var allocFrom *string
+var allocIdent *string
+var allocReserved *bool
+var allocAzureSubscription *string
+var allocAzureResourceGroup *string
+var allocAzureVirtualNetwork *string
+var allocGit *string
+var allocDocumentationURI *string
+var allocAWSCloudFormationURL *string
 
 func init() {
 	rootCmd.AddCommand(validateCmd)
@@ -258,10 +774,34 @@ func init() {
 	inputFilename = rootCmd.PersistentFlags().StringP("input-file", "i", "-", "input file")
 	outputFilename = rootCmd.PersistentFlags().StringP("output-file", "o", "-", "output file")
 
+	validateRules = validateCmd.Flags().StringSlice("rules", nil, "turn validation rules on (name) or off (-name), all and none switch every rule ("+ruleNames()+")")
+
+	validateOutput = validateCmd.Flags().String("output", "text", "output format of the findings (text, json), json is an object for a file and a list of them for a directory")
+
 	renderFree = renderCmd.Flags().BoolP("all-blocks", "a", false, "include free blocks when rendering")
-	renderFormat = renderCmd.Flags().StringP("render-format", "f", "markdown", "render format (markdown)")
+	renderCmd.Flags().StringVarP(renderFormat, "render-format", "f", "markdown", "render format (markdown, json, csv, tsv, html, svg, template)")
+	renderDir = renderCmd.Flags().String("dir", "", "render every atf file in this directory, see --out")
+	renderOut = renderCmd.Flags().String("out", "", "directory the files rendered with --dir and an index.md are written to")
+	renderCollapseFree = renderCmd.Flags().Bool("collapse-free", false, "include free space in markdown, consecutive free blocks collapsed into one range")
+	renderCmd.Flags().StringSliceVar(renderColumns, "columns", []string{"azure"}, "markdown columns, azure, aws, generic or a list of: "+strings.Join(render.MarkdownColumnNames(), ", "))
+	renderCmd.Flags().StringVar(renderTemplateFile, "template", "", "text/template file for the template render format")
 
 	allocSize = allocCmd.Flags().IntP("size", "s", -1, "size of the network to allocate")
+	allocHosts = allocCmd.Flags().Int("hosts", -1, "allocate the smallest network with this many usable hosts instead of --size")
+	allocProvider = allocCmd.Flags().String("provider", "", "provider whose reserved addresses --hosts accounts for, overrides the file's default ("+strings.Join(netcalc.ProviderNames, ", ")+")")
 	allocDesc = allocCmd.Flags().StringP("description", "d", "", "description for the newly allocated subnet")
-	inPlace = allocCmd.PersistentFlags().Bool("in-place", false, "modify the input file in place")
+	allocIdent = allocCmd.Flags().String("ident", "", "ident for the newly allocated subnet, must be unique in the file")
+	allocReserved = allocCmd.Flags().Bool("reserved", false, "mark the newly allocated subnet as reserved")
+	allocAzureSubscription = allocCmd.Flags().String("azure-subscription", "", "azure subscription the subnet is used in")
+	allocAzureResourceGroup = allocCmd.Flags().String("azure-resource-group", "", "azure resource group the subnet is used in")
+	allocAzureVirtualNetwork = allocCmd.Flags().String("azure-vnet", "", "azure virtual network the subnet is used in")
+	allocGit = allocCmd.Flags().String("git", "", "git repository managing the subnet")
+	allocDocumentationURI = allocCmd.Flags().String("doc-url", "", "url of the subnet's documentation")
+	allocAWSCloudFormationURL = allocCmd.Flags().String("aws-cfn-url", "", "url of the aws cloudformation stack using the subnet")
+	allocCidr = allocCmd.Flags().StringP("cidr", "c", "", "claim exactly this network instead of finding a free one")
+	allocStrategy = allocCmd.Flags().String("strategy", "", "allocation strategy, overrides the file's default ("+strings.Join(netcalc.StrategyNames, ", ")+")")
+	allocParent = allocCmd.Flags().StringP("parent", "p", "", "allocate from inside the allocation with this cidr or ident")
+	allocSuperblock = allocCmd.Flags().String("superblock", "", "allocate from this superblock only, by default the strategy picks from all superblocks")
+	allocFrom = allocCmd.Flags().String("from", "", "allocate every request of this manifest at once, largest first, all or nothing")
+	allocCmd.PersistentFlags().BoolVar(inPlace, "in-place", false, "modify the input file in place")
 }
@@ -0,0 +1,219 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package atfutil
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"atfutil/pkg/atf"
+	"atfutil/pkg/netpool"
+
+	"gopkg.in/yaml.v3"
+)
+
+func parseText(t *testing.T, text string) *netpool.ParsedATF {
+	t.Helper()
+	doc, err := atf.ParseDocument([]byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := netpool.FromAtf(doc.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func TestAllocPools(t *testing.T) {
+	parsed := parseText(t, `superBlocks:
+  - 10.0.0.0/22
+  - 10.1.0.0/22
+allocations:
+- cidr: 10.0.0.0/24
+  ident: zone
+  subAlloc:
+  - cidr: 10.0.0.0/26
+    ident: app
+- cidr: 10.1.0.0/24
+  ident: empty
+`)
+	tests := []struct {
+		name       string
+		parent     string
+		superblock string
+		// want are the superblocks of the returned pools, nil for an error
+		want []string
+	}{
+		{"all superblocks", "", "", []string{"10.0.0.0/22", "10.1.0.0/22"}},
+		{"one superblock", "", "10.1.0.0/22", []string{"10.1.0.0/22"}},
+		{"unknown superblock", "", "10.2.0.0/22", nil},
+		{"parent with suballocations", "zone", "", []string{"10.0.0.0/24"}},
+		{"parent without suballocations", "empty", "", []string{"10.1.0.0/24"}},
+		{"parent and superblock", "zone", "10.0.0.0/22", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var parent *atf.Allocation
+			if tt.parent != "" {
+				parent = parsed.FindAllocation(tt.parent)
+			}
+			pools, err := allocPools(parsed, parent, tt.superblock)
+			if tt.want == nil {
+				if err == nil {
+					t.Errorf("expected an error, got %d pools", len(pools))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(pools) != len(tt.want) {
+				t.Fatalf("got %d pools, want %d", len(pools), len(tt.want))
+			}
+			for i, pool := range pools {
+				if pool.Super().String() != tt.want[i] {
+					t.Errorf("pool %d is %s, want %s", i, pool.Super(), tt.want[i])
+				}
+			}
+		})
+	}
+
+	// allocating in the parent created on demand keeps it in the parsed file
+	pools, err := allocPools(parsed, parsed.FindAllocation("empty"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pools[0].Alloc(26); err != nil {
+		t.Fatal(err)
+	}
+	if !parsed.GetPoolByNet("10.1.0.0/24").FindAllAllocations()[0].Alloc {
+		t.Error("the allocation in the new pool of empty is lost")
+	}
+}
+
+func TestCheckIdent(t *testing.T) {
+	parsed := parseText(t, "superBlock: 10.0.0.0/22\nallocations:\n- cidr: 10.0.0.0/24\n  ident: zone\n  subAlloc:\n  - cidr: 10.0.0.0/26\n    ident: app\n- cidr: 10.0.1.0/24\n")
+	tests := []struct {
+		ident   string
+		wantErr bool
+	}{
+		{"zone", true},
+		{"app", true},
+		{"web", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if err := checkIdent(parsed, tt.ident); (err != nil) != tt.wantErr {
+			t.Errorf("checkIdent(%q) error = %v, wantErr %v", tt.ident, err, tt.wantErr)
+		}
+	}
+}
+
+// setFlag sets the value behind a string flag pointer until the test ends
+func setFlag(t *testing.T, flag *string, value string) {
+	old := *flag
+	*flag = value
+	t.Cleanup(func() { *flag = old })
+}
+
+func TestNewAllocation(t *testing.T) {
+	network := &atf.IPNet{}
+	if err := network.UnmarshalText([]byte("10.0.0.0/24")); err != nil {
+		t.Fatal(err)
+	}
+
+	setFlag(t, allocIdent, "web")
+	setFlag(t, allocAzureSubscription, "sub-1")
+	setFlag(t, allocAzureResourceGroup, "rg-web")
+	setFlag(t, allocAzureVirtualNetwork, "vnet-web")
+	setFlag(t, allocAWSCloudFormationURL, "https://example.com/stack")
+	alloc := newAllocation(network)
+	if alloc.Ident != "web" || alloc.Network != network {
+		t.Errorf("unexpected allocation %+v", alloc)
+	}
+	want := atf.Reference{
+		AWS:   atf.ReferenceAWS{CloudFormationURL: "https://example.com/stack"},
+		Azure: atf.ReferenceAzure{Subscription: "sub-1", ResourceGroup: "rg-web", VirtualNetwork: "vnet-web"},
+	}
+	if !reflect.DeepEqual(alloc.Reference, want) {
+		t.Errorf("Reference = %+v, want %+v", alloc.Reference, want)
+	}
+
+	// unset --git and --doc-url leave no empty keys in the file
+	out, err := yaml.Marshal(alloc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"git:", "documentedAt:"} {
+		if strings.Contains(string(out), key) {
+			t.Errorf("unset flag wrote %s\n%s", key, out)
+		}
+	}
+
+	setFlag(t, allocGit, "https://example.com/web.git")
+	setFlag(t, allocDocumentationURI, "https://example.com/web")
+	alloc = newAllocation(network)
+	if alloc.Reference.Git == nil || *alloc.Reference.Git != "https://example.com/web.git" ||
+		alloc.Reference.DocumentationURI == nil || *alloc.Reference.DocumentationURI != "https://example.com/web" {
+		t.Errorf("unexpected references %+v", alloc.Reference)
+	}
+}
+
+func TestOptionalString(t *testing.T) {
+	if optionalString("") != nil {
+		t.Error("empty value is not nil")
+	}
+	if value := optionalString("x"); value == nil || *value != "x" {
+		t.Errorf("optionalString(\"x\") = %v", value)
+	}
+}
+
+func TestLoadFile_Positions(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"superBlocks:\n- 10.0.0.0/16\n- 10.0.4.0/22\nallocations: []\n", "3:3: superblock 10.0.4.0/22 overlaps with superblock 10.0.0.0/16"},
+		{"superBlock: 10.0.0.0/16\nstrategy: sideways\nallocations: []\n", "2:11: "},
+		{"superBlock: 10.0.0.0/16\nprovider: nimbus\nallocations: []\n", "2:11: "},
+	}
+	for _, tt := range tests {
+		file, err := ioutil.TempFile("", "positions*.yaml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(file.Name())
+		if _, err := file.WriteString(tt.text); err != nil {
+			t.Fatal(err)
+		}
+		file.Close()
+
+		_, _, err = loadFile(file.Name())
+		if err == nil || !strings.HasPrefix(err.Error(), tt.expected) {
+			t.Errorf("expected error starting with %q for %q, got %v", tt.expected, tt.text, err)
+		}
+	}
+}
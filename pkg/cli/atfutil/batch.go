@@ -0,0 +1,186 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package atfutil
+
+import (
+	"io/ioutil"
+	"net"
+	"sort"
+
+	"atfutil/pkg/atf"
+	"atfutil/pkg/netcalc"
+	"atfutil/pkg/netpool"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// allocFlags are the alloc flags describing a single network, a manifest
+// replaces them
+var allocFlags = []string{
+	"size", "hosts", "cidr", "ident", "description", "parent", "superblock", "reserved",
+	"azure-subscription", "azure-resource-group", "azure-vnet", "git", "doc-url", "aws-cfn-url",
+}
+
+// allocBatch allocates every request of the manifest, the largest networks
+// first to pack them tightly. Either all requests are allocated or the file
+// is left as it was.
+func allocBatch(cmd *cobra.Command, doc *atf.Document, parsed *netpool.ParsedATF, manifestName string) (err error) {
+	for _, name := range allocFlags {
+		if cmd.Flags().Changed(name) {
+			return errors.Errorf("cannot use --%s and --from at the same time", name)
+		}
+	}
+
+	data, err := ioutil.ReadFile(manifestName)
+	if err != nil {
+		return err
+	}
+	requests, err := atf.ParseRequests(data)
+	if err != nil {
+		return &fileError{name: manifestName, err: err}
+	}
+
+	strategy := parsed.Strategy
+	if *allocStrategy != "" {
+		strategy, err = netcalc.StrategyByName(*allocStrategy)
+		if err != nil {
+			return err
+		}
+	}
+	provider := parsed.Provider
+	if *allocProvider != "" {
+		provider, err = netcalc.ProviderByName(*allocProvider)
+		if err != nil {
+			return err
+		}
+	}
+
+	byIdent := make(map[string]*atf.Request, len(requests))
+	for _, request := range requests {
+		if parsed.GetAtfAllocationByIdent(request.Ident) != nil {
+			return &fileError{name: manifestName, err: request.Errorf("ident '%s' is already in use", request.Ident)}
+		}
+		byIdent[request.Ident] = request
+	}
+	sizes := make(map[*atf.Request]int, len(requests))
+	for _, request := range requests {
+		sizes[request] = request.Size
+		if request.Hosts != 0 {
+			family, err := requestFamily(parsed, byIdent, request, len(requests))
+			if err == nil {
+				sizes[request], err = hostsPrefixLen(family, request.Hosts, provider)
+			}
+			if err != nil {
+				return &fileError{name: manifestName, err: request.Errorf("%s", err)}
+			}
+		}
+	}
+	ordered := append([]*atf.Request(nil), requests...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return sizes[ordered[i]] < sizes[ordered[j]]
+	})
+
+	// allocations are only ever appended, so cutting the lists back to their
+	// old length undoes a failed batch
+	top := doc.File.Allocations
+	subs := make(map[*atf.Allocation][]*atf.Allocation)
+	defer func() {
+		if err != nil {
+			doc.File.Allocations = top
+			for parent, sub := range subs {
+				parent.SubAlloc = sub
+			}
+		}
+	}()
+
+	for _, request := range ordered {
+		if parent := parsed.FindAllocation(request.Parent); request.Parent != "" && parent != nil {
+			if _, ok := subs[parent]; !ok {
+				subs[parent] = parent.SubAlloc
+			}
+		}
+		alloc, err := allocRequest(parsed, request, sizes[request], strategy)
+		if err != nil {
+			return &fileError{name: manifestName, err: request.Errorf("cannot allocate /%d: %s", sizes[request], err)}
+		}
+		// later requests may be allocated inside this one, so the pools are
+		// built again
+		parsed, err = netpool.FromAtf(doc.File)
+		if err != nil {
+			return errors.Wrapf(err, "allocating %s", alloc.Network.String())
+		}
+	}
+	return nil
+}
+
+// requestFamily returns a network of the address family the request will be
+// allocated in: that of its superblock, its parent or of all superblocks.
+// Parents can be requests of the same manifest, depth guards against cycles.
+func requestFamily(parsed *netpool.ParsedATF, byIdent map[string]*atf.Request, request *atf.Request, depth int) (*net.IPNet, error) {
+	if request.Parent != "" {
+		if parent := parsed.FindAllocation(request.Parent); parent != nil {
+			return parent.Network.IPNet, nil
+		}
+		parentRequest, ok := byIdent[request.Parent]
+		if !ok {
+			return nil, errors.Errorf("no allocation matches parent '%s'", request.Parent)
+		}
+		if depth == 0 {
+			return nil, errors.Errorf("parent '%s' is nested in its own request", request.Parent)
+		}
+		return requestFamily(parsed, byIdent, parentRequest, depth-1)
+	}
+	pools, err := allocPools(parsed, nil, request.Superblock)
+	if err != nil {
+		return nil, err
+	}
+	return poolsFamily(pools)
+}
+
+// allocRequest allocates a network for the request and adds it to the file
+func allocRequest(parsed *netpool.ParsedATF, request *atf.Request, size int, strategy netcalc.Strategy) (*atf.Allocation, error) {
+	var parentAlloc *atf.Allocation
+	if request.Parent != "" {
+		parentAlloc = parsed.FindAllocation(request.Parent)
+		if parentAlloc == nil {
+			return nil, errors.Errorf("no allocation matches parent '%s'", request.Parent)
+		}
+	}
+	pools, err := allocPools(parsed, parentAlloc, request.Superblock)
+	if err != nil {
+		return nil, err
+	}
+	ipnet, err := allocSized(pools, size, strategy)
+	if err != nil {
+		return nil, err
+	}
+
+	alloc := request.Allocation()
+	alloc.Network = &atf.IPNet{IPNet: ipnet}
+	if parentAlloc != nil {
+		parentAlloc.SubAlloc = append(parentAlloc.SubAlloc, alloc)
+	} else {
+		parsed.File.Allocations = append(parsed.File.Allocations, alloc)
+	}
+	return alloc, nil
+}
@@ -0,0 +1,154 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package atfutil
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"atfutil/pkg/atf"
+	"atfutil/pkg/netpool"
+)
+
+// runBatch allocates the manifest in the file and returns the document
+func runBatch(t *testing.T, fileText string, manifest string) (*atf.Document, error) {
+	t.Helper()
+	manifestFile, err := ioutil.TempFile("", "requests*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(manifestFile.Name())
+	if _, err := manifestFile.WriteString(manifest); err != nil {
+		t.Fatal(err)
+	}
+	manifestFile.Close()
+
+	doc, err := atf.ParseDocument([]byte(fileText))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := netpool.FromAtf(doc.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc, allocBatch(allocCmd, doc, parsed, manifestFile.Name())
+}
+
+func expectNetworks(t *testing.T, doc *atf.Document, want map[string]string) {
+	t.Helper()
+	parsed, err := netpool.FromAtf(doc.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ident, cidr := range want {
+		alloc := parsed.GetAtfAllocationByIdent(ident)
+		if alloc == nil {
+			t.Errorf("%s was not allocated", ident)
+		} else if alloc.Network.String() != cidr {
+			t.Errorf("%s got %s, want %s", ident, alloc.Network.String(), cidr)
+		}
+	}
+}
+
+func TestAllocBatch_LargestFirst(t *testing.T) {
+	// in manifest order small would take 10.0.0.0/25 and push big up
+	doc, err := runBatch(t, "superBlock: 10.0.0.0/23\nallocations: []\n", `requests:
+  - ident: small
+    size: 25
+  - ident: big
+    size: 24
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectNetworks(t, doc, map[string]string{"big": "10.0.0.0/24", "small": "10.0.1.0/25"})
+}
+
+func TestAllocBatch_ParentInManifest(t *testing.T) {
+	// the hosts request is sized in the family of its parent, although the
+	// file has IPv4 and IPv6 superblocks
+	doc, err := runBatch(t, "superBlocks:\n  - 10.0.0.0/16\n  - fd00::/48\nallocations: []\n", `requests:
+  - ident: app
+    hosts: 200
+    parent: zone
+    description: application servers
+  - ident: zone
+    size: 20
+    superblock: 10.0.0.0/16
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectNetworks(t, doc, map[string]string{"zone": "10.0.0.0/20", "app": "10.0.0.0/24"})
+	zone := doc.File.Allocations[0]
+	if zone.Ident != "zone" || len(zone.SubAlloc) != 1 || zone.SubAlloc[0].Description != "application servers" {
+		t.Errorf("app is not a suballocation of zone: %+v", doc.File.Allocations)
+	}
+}
+
+func TestAllocBatch_Atomic(t *testing.T) {
+	fileText := `superBlock: 10.0.0.0/24
+allocations:
+- cidr: 10.0.0.0/25
+  ident: zone
+`
+	// wide and sub fit, full doesn't fit anymore
+	doc, err := runBatch(t, fileText, `requests:
+  - ident: sub
+    size: 26
+    parent: zone
+  - ident: wide
+    size: 25
+  - ident: full
+    size: 26
+`)
+	if err == nil {
+		t.Fatal("expected the batch to fail")
+	}
+	if !strings.Contains(err.Error(), ": 7:") {
+		t.Errorf("expected an error at the full request, got %v", err)
+	}
+
+	out, err := doc.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != fileText {
+		t.Errorf("failed batch changed the file:\n%s", out)
+	}
+}
+
+func TestAllocBatch_Conflicts(t *testing.T) {
+	for _, manifest := range []string{
+		// ident of the file
+		"requests:\n  - ident: zone\n    size: 26\n",
+		// unknown parent
+		"requests:\n  - ident: app\n    hosts: 10\n    parent: nope\n",
+	} {
+		_, err := runBatch(t, "superBlock: 10.0.0.0/24\nallocations:\n- cidr: 10.0.0.0/25\n  ident: zone\n", manifest)
+		if err == nil {
+			t.Errorf("expected an error for %q", manifest)
+		}
+	}
+}
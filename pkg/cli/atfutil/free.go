@@ -0,0 +1,133 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package atfutil
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"atfutil/pkg/netcalc"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var freeCmd = &cobra.Command{
+	Use:   "free",
+	Short: "list free space by size",
+	Long:  "list the free blocks of the superblocks or of an allocation grouped by prefix length, with --size tell how many networks of that size still fit",
+	Run: func(cmd *cobra.Command, args []string) {
+		_, pool, err := loadInput()
+		if err != nil {
+			quitWithError(err)
+		}
+
+		freePools := pool.Pools
+		if *freeParent != "" {
+			parent := pool.FindAllocation(*freeParent)
+			if parent == nil {
+				quitWithError(errors.Errorf("no allocation matches parent '%s'", *freeParent))
+			}
+			freePool, err := pool.GetOrCreatePoolByNet(parent.Network.String())
+			if err != nil {
+				quitWithError(err)
+			}
+			freePools = []*netcalc.IPNetPool{freePool}
+		}
+		if *freeSize != -1 {
+			valid := false
+			for _, freePool := range freePools {
+				valid = valid || *freeSize <= netcalc.MaskBits(freePool.Super())
+			}
+			if *freeSize < 0 || !valid {
+				quitWithError(errors.Errorf("invalid prefix length /%d", *freeSize))
+			}
+		}
+
+		outFile, err := getOutputFile(*outputFilename)
+		if err != nil {
+			quitWithError(err)
+		}
+		defer outFile.Close()
+
+		fits := new(big.Int)
+		for i, freePool := range freePools {
+			if i > 0 {
+				fmt.Fprintln(outFile)
+			}
+			printFree(outFile, freePool)
+			if *freeSize != -1 && *freeSize <= netcalc.MaskBits(freePool.Super()) {
+				fits.Add(fits, freePool.Capacity(*freeSize))
+			}
+		}
+
+		if *freeSize != -1 {
+			fmt.Fprintf(outFile, "%s more /%d networks fit\n", fits, *freeSize)
+		}
+		os.Exit(0)
+	},
+}
+
+// printFree prints the free blocks of a pool grouped by prefix length
+func printFree(w io.Writer, freePool *netcalc.IPNetPool) {
+	super := freePool.Super()
+	blocks := freePool.FreeBlocks()
+	total := new(big.Int)
+	byPrefix := make(map[int][]*net.IPNet)
+	for _, block := range blocks {
+		total.Add(total, netcalc.AddressCount(block))
+		prefixLen, _ := block.Mask.Size()
+		byPrefix[prefixLen] = append(byPrefix[prefixLen], block)
+	}
+	prefixLens := make([]int, 0, len(byPrefix))
+	for prefixLen := range byPrefix {
+		prefixLens = append(prefixLens, prefixLen)
+	}
+	sort.Ints(prefixLens)
+
+	fmt.Fprintf(w, "free space in %s: %s of %s addresses\n", super.String(), total, netcalc.AddressCount(super))
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, prefixLen := range prefixLens {
+		nets := make([]string, 0, len(byPrefix[prefixLen]))
+		for _, block := range byPrefix[prefixLen] {
+			nets = append(nets, block.String())
+		}
+		fmt.Fprintf(tw, "/%d\t%d\t%s\n", prefixLen, len(nets), strings.Join(nets, ", "))
+	}
+	tw.Flush()
+}
+
+var freeParent *string
+var freeSize *int
+
+func init() {
+	rootCmd.AddCommand(freeCmd)
+
+	freeParent = freeCmd.Flags().StringP("parent", "p", "", "list free space inside the allocation with this cidr or ident")
+	freeSize = freeCmd.Flags().IntP("size", "s", -1, "count how many networks with this prefix length still fit")
+}
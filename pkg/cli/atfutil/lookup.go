@@ -0,0 +1,154 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package atfutil
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+
+	"atfutil/pkg/atf"
+	"atfutil/pkg/netpool"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var lookupCmd = &cobra.Command{
+	Use:   "lookup <ip|cidr> [files...]",
+	Short: "find the allocation an address belongs to",
+	Long:  "find the allocations containing an address or network, from the superblock down to the innermost suballocation, in the given files or the input file",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ipnet, err := parseAddress(args[0])
+		if err != nil {
+			quitWithError(err)
+		}
+		files := args[1:]
+		if len(files) == 0 {
+			files = []string{*inputFilename}
+		}
+
+		outFile, err := getOutputFile(*outputFilename)
+		if err != nil {
+			quitWithError(err)
+		}
+		defer outFile.Close()
+
+		found := false
+		for _, name := range files {
+			_, pool, err := loadFile(name)
+			if err != nil {
+				quitWithError(&fileError{name: name, err: err})
+			}
+			result := pool.Lookup(ipnet)
+			if result == nil {
+				continue
+			}
+			found = true
+			printLookup(outFile, name, pool, result)
+		}
+		if !found {
+			fmt.Fprintf(outFile, "%s is outside every superblock\n", args[0])
+		}
+		os.Exit(0)
+	},
+}
+
+// parseAddress parses a CIDR or a single address, which is treated as a
+// network of one address
+func parseAddress(s string) (*net.IPNet, error) {
+	if strings.Contains(s, "/") {
+		_, ipnet, err := net.ParseCIDR(s)
+		return ipnet, err
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, errors.Errorf("'%s' is neither an IP address nor a CIDR", s)
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
+
+func printLookup(w io.Writer, name string, pool *netpool.ParsedATF, result *netpool.LookupResult) {
+	fmt.Fprintf(w, "%s\n", displayName(name))
+	superblock := result.Superblock.String()
+	if pool.File.Name != nil {
+		superblock += " " + *pool.File.Name
+	}
+	fmt.Fprintf(w, "  superblock %s\n", superblock)
+
+	indent := "  "
+	for _, alloc := range result.Chain {
+		indent += "  "
+		line := fmt.Sprintf("%s↳ %s", indent, alloc.Network.String())
+		if alloc.Ident != "" {
+			line += " " + alloc.Ident
+		}
+		if alloc.IsReserved {
+			line += " (reserved)"
+		}
+		if alloc.Description != "" {
+			line += ": " + alloc.Description
+		}
+		fmt.Fprintln(w, line)
+		for _, ref := range lookupReferences(&alloc.Reference) {
+			fmt.Fprintf(w, "%s    %s\n", indent, ref)
+		}
+	}
+
+	indent += "  "
+	switch {
+	case result.Free != nil:
+		fmt.Fprintf(w, "%s↳ free block %s\n", indent, result.Free.String())
+	case result.Split:
+		fmt.Fprintf(w, "%s↳ spans several blocks\n", indent)
+	}
+}
+
+func lookupReferences(ref *atf.Reference) []string {
+	refs := make([]string, 0)
+	add := func(name string, value string) {
+		if value != "" {
+			refs = append(refs, name+": "+value)
+		}
+	}
+	add("subscription", ref.Azure.Subscription)
+	add("resource group", ref.Azure.ResourceGroup)
+	add("vnet", ref.Azure.VirtualNetwork)
+	add("cloudformation", ref.AWS.CloudFormationURL)
+	if ref.Git != nil {
+		add("git", *ref.Git)
+	}
+	if ref.DocumentationURI != nil {
+		add("documentation", *ref.DocumentationURI)
+	}
+	return refs
+}
+
+func init() {
+	rootCmd.AddCommand(lookupCmd)
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package atfutil
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var releaseCmd = &cobra.Command{
+	Use:   "release <cidr|ident>",
+	Short: "release an allocation",
+	Long:  "release an allocation given by cidr or ident, allocations with suballocations are only released with --recursive",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := checkInPlace()
+		if err != nil {
+			quitWithError(err)
+		}
+
+		doc, pool, err := loadInput()
+		if err != nil {
+			quitWithError(err)
+		}
+
+		alloc := pool.FindAllocation(args[0])
+		if alloc == nil {
+			quitWithError(errors.Errorf("no allocation matches '%s'", args[0]))
+		}
+		err = pool.Release(alloc.Network.String(), *releaseRecursive)
+		if err != nil {
+			quitWithError(err)
+		}
+
+		err = writeAtfFile(doc)
+		if err != nil {
+			quitWithError(err)
+		}
+
+		os.Exit(0)
+	},
+}
+
+var releaseRecursive *bool
+
+func init() {
+	rootCmd.AddCommand(releaseCmd)
+
+	releaseRecursive = releaseCmd.Flags().BoolP("recursive", "r", false, "also release all suballocations")
+	releaseCmd.Flags().BoolVar(inPlace, "in-place", false, "modify the input file in place")
+}
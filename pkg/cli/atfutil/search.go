@@ -0,0 +1,171 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package atfutil
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"atfutil/pkg/atf"
+	"atfutil/pkg/render"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var searchCmd = &cobra.Command{
+	Use:   "search",
+	Short: "find allocations by their metadata",
+	Long:  "find allocations by their metadata and render the matches, all given filters have to match",
+	Run: func(cmd *cobra.Command, args []string) {
+		_, pool, err := loadInput()
+		if err != nil {
+			quitWithError(err)
+		}
+
+		filters, err := searchFilters(cmd)
+		if err != nil {
+			quitWithError(err)
+		}
+		if *searchParent != "" {
+			parent := pool.FindAllocation(*searchParent)
+			if parent == nil {
+				quitWithError(errors.Errorf("no allocation matches parent '%s'", *searchParent))
+			}
+			inside := make(map[*atf.Allocation]bool)
+			markNested(parent.SubAlloc, inside)
+			filters = append(filters, func(alloc *atf.Allocation) bool { return inside[alloc] })
+		}
+
+		matches, err := pool.Filter(func(alloc *atf.Allocation) bool {
+			for _, filter := range filters {
+				if !filter(alloc) {
+					return false
+				}
+			}
+			return true
+		})
+		if err != nil {
+			quitWithError(err)
+		}
+
+		outBuffer := &bytes.Buffer{}
+		err = renderPool(outBuffer, matches, false)
+		if err != nil {
+			quitWithError(err)
+		}
+
+		outFile, err := getOutputFile(*outputFilename)
+		if err != nil {
+			quitWithError(err)
+		}
+		defer outFile.Close()
+		_, err = io.Copy(outFile, outBuffer)
+		if err != nil {
+			quitWithError(err)
+		}
+
+		os.Exit(0)
+	},
+}
+
+// searchFilters builds one filter per given flag
+func searchFilters(cmd *cobra.Command) ([]func(alloc *atf.Allocation) bool, error) {
+	filters := make([]func(alloc *atf.Allocation) bool, 0)
+	equal := func(flag string, value *string, field func(alloc *atf.Allocation) string) {
+		if cmd.Flags().Changed(flag) {
+			filters = append(filters, func(alloc *atf.Allocation) bool { return field(alloc) == *value })
+		}
+	}
+	equal("ident", searchIdent, func(alloc *atf.Allocation) string { return alloc.Ident })
+	equal("azure-subscription", searchAzureSubscription, func(alloc *atf.Allocation) string { return alloc.Reference.Azure.Subscription })
+	equal("azure-resource-group", searchAzureResourceGroup, func(alloc *atf.Allocation) string { return alloc.Reference.Azure.ResourceGroup })
+	equal("azure-vnet", searchAzureVirtualNetwork, func(alloc *atf.Allocation) string { return alloc.Reference.Azure.VirtualNetwork })
+
+	if *searchDescription != "" {
+		needle := strings.ToLower(*searchDescription)
+		filters = append(filters, func(alloc *atf.Allocation) bool {
+			return strings.Contains(strings.ToLower(alloc.Description), needle)
+		})
+	}
+	if *searchDescriptionRegex != "" {
+		re, err := regexp.Compile(*searchDescriptionRegex)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid --description-regex")
+		}
+		filters = append(filters, func(alloc *atf.Allocation) bool { return re.MatchString(alloc.Description) })
+	}
+	if cmd.Flags().Changed("reserved") {
+		filters = append(filters, func(alloc *atf.Allocation) bool { return alloc.IsReserved == *searchReserved })
+	}
+	if *searchMinPrefix > 0 || *searchMaxPrefix > 0 {
+		filters = append(filters, func(alloc *atf.Allocation) bool {
+			prefixLen, _ := alloc.Network.Mask.Size()
+			if *searchMinPrefix > 0 && prefixLen < *searchMinPrefix {
+				return false
+			}
+			return *searchMaxPrefix <= 0 || prefixLen <= *searchMaxPrefix
+		})
+	}
+	return filters, nil
+}
+
+// markNested marks all given allocations and everything nested in them
+func markNested(allocations []*atf.Allocation, marked map[*atf.Allocation]bool) {
+	for _, alloc := range allocations {
+		marked[alloc] = true
+		markNested(alloc.SubAlloc, marked)
+	}
+}
+
+var searchIdent *string
+var searchDescription *string
+var searchDescriptionRegex *string
+var searchReserved *bool
+var searchAzureSubscription *string
+var searchAzureResourceGroup *string
+var searchAzureVirtualNetwork *string
+var searchMinPrefix *int
+var searchMaxPrefix *int
+var searchParent *string
+
+func init() {
+	rootCmd.AddCommand(searchCmd)
+
+	searchIdent = searchCmd.Flags().String("ident", "", "only allocations with this ident")
+	searchDescription = searchCmd.Flags().String("description", "", "only allocations whose description contains this text, ignoring case")
+	searchDescriptionRegex = searchCmd.Flags().String("description-regex", "", "only allocations whose description matches this regular expression")
+	searchReserved = searchCmd.Flags().Bool("reserved", false, "only reserved allocations, --reserved=false for only unreserved ones")
+	searchAzureSubscription = searchCmd.Flags().String("azure-subscription", "", "only allocations in this azure subscription")
+	searchAzureResourceGroup = searchCmd.Flags().String("azure-resource-group", "", "only allocations in this azure resource group")
+	searchAzureVirtualNetwork = searchCmd.Flags().String("azure-vnet", "", "only allocations in this azure virtual network")
+	searchMinPrefix = searchCmd.Flags().Int("min-prefix", 0, "only allocations with a prefix length of at least this")
+	searchMaxPrefix = searchCmd.Flags().Int("max-prefix", 0, "only allocations with a prefix length of at most this")
+	searchParent = searchCmd.Flags().StringP("parent", "p", "", "only allocations nested in the allocation with this cidr or ident")
+
+	searchCmd.Flags().StringVarP(renderFormat, "render-format", "f", "markdown", "render format (markdown, json, csv, tsv, html, svg, template)")
+	searchCmd.Flags().StringSliceVar(renderColumns, "columns", []string{"azure"}, "markdown columns, azure, aws, generic or a list of: "+strings.Join(render.MarkdownColumnNames(), ", "))
+	searchCmd.Flags().StringVar(renderTemplateFile, "template", "", "text/template file for the template render format")
+}
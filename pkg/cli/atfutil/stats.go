@@ -0,0 +1,108 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package atfutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"atfutil/pkg/netpool"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "show utilisation statistics",
+	Long:  "show address usage, the largest free block, fragmentation and allocation sizes of the superblock and of every allocation with suballocations",
+	Run: func(cmd *cobra.Command, args []string) {
+		if *statsOutput != "text" && *statsOutput != "json" {
+			quitWithError(errors.Errorf("unknown output format '%s'", *statsOutput))
+		}
+
+		_, pool, err := loadInput()
+		if err != nil {
+			quitWithError(err)
+		}
+		stats := pool.Stats()
+
+		outFile, err := getOutputFile(*outputFilename)
+		if err != nil {
+			quitWithError(err)
+		}
+		defer outFile.Close()
+
+		if *statsOutput == "json" {
+			enc := json.NewEncoder(outFile)
+			enc.SetIndent("", "  ")
+			err = enc.Encode(stats)
+			if err != nil {
+				quitWithError(err)
+			}
+		} else {
+			printStats(outFile, stats)
+		}
+		os.Exit(0)
+	},
+}
+
+func printStats(w io.Writer, stats []*netpool.Stats) {
+	for _, s := range stats {
+		indent := strings.Repeat("  ", s.Depth)
+		title := s.CIDR
+		if s.Ident != "" {
+			title += " " + s.Ident
+		}
+		fmt.Fprintf(w, "%s%s\n", indent, title)
+		fmt.Fprintf(w, "%s  addresses: %s total, %s allocated, %s reserved, %s free (%.1f%% used)\n",
+			indent, s.Total, s.Allocated, s.Reserved, s.Free, s.UsedPercent)
+		if s.LargestFree != "" {
+			fmt.Fprintf(w, "%s  largest free block: %s, fragmentation %.2f\n", indent, s.LargestFree, s.Fragmentation)
+		}
+
+		prefixLens := make([]int, 0, len(s.Histogram))
+		for prefixLen := range s.Histogram {
+			prefixLens = append(prefixLens, prefixLen)
+		}
+		sort.Ints(prefixLens)
+		sizes := make([]string, 0, len(prefixLens))
+		for _, prefixLen := range prefixLens {
+			sizes = append(sizes, fmt.Sprintf("/%d: %d", prefixLen, s.Histogram[prefixLen]))
+		}
+		if len(sizes) > 0 {
+			fmt.Fprintf(w, "%s  allocation sizes: %s\n", indent, strings.Join(sizes, ", "))
+		}
+	}
+}
+
+var statsOutput *string
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+
+	statsOutput = statsCmd.Flags().String("output", "text", "output format (text, json)")
+}
@@ -0,0 +1,335 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package atfutil
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"atfutil/pkg/atf"
+	"atfutil/pkg/netpool"
+
+	"github.com/pkg/errors"
+)
+
+// a workspace is a directory tree of atf files, they are found by these
+// suffixes
+var atfFileSuffixes = []string{".atf.yaml", ".atf.yml"}
+
+// renderExtensions are the file extensions of rendered workspace files
+var renderExtensions = map[string]string{
+	"markdown": ".md",
+	"json":     ".json",
+	"csv":      ".csv",
+	"tsv":      ".tsv",
+	"html":     ".html",
+	"svg":      ".svg",
+}
+
+func isDir(name string) bool {
+	info, err := os.Stat(name)
+	return err == nil && info.IsDir()
+}
+
+// findAtfFiles returns all atf files below root, sorted
+func findAtfFiles(root string) ([]string, error) {
+	files := make([]string, 0)
+	err := filepath.Walk(root, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && atfBaseName(name) != "" {
+			files = append(files, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// atfBaseName returns the file name without atf suffix, "" for other files
+func atfBaseName(name string) string {
+	base := filepath.Base(name)
+	for _, suffix := range atfFileSuffixes {
+		if strings.HasSuffix(base, suffix) && len(base) > len(suffix) {
+			return strings.TrimSuffix(base, suffix)
+		}
+	}
+	return ""
+}
+
+// workspaceDocument is a successfully loaded file of a workspace
+type workspaceDocument struct {
+	name string
+	doc  *atf.Document
+}
+
+// superblockOverlaps returns findings for every file with a superblock that
+// overlaps a superblock of another file, by file name
+func superblockOverlaps(docs []*workspaceDocument) map[string][]*atf.Finding {
+	overlaps := make(map[string][]*atf.Finding)
+	for i, a := range docs {
+		for _, b := range docs[:i] {
+			for j, superA := range a.doc.File.AllSuperblocks() {
+				for _, superB := range b.doc.File.AllSuperblocks() {
+					if !superA.Contains(superB.IP) && !superB.Contains(superA.IP) {
+						continue
+					}
+					overlaps[a.name] = append(overlaps[a.name], &atf.Finding{
+						Rule:     "superblock-overlap",
+						Severity: atf.SeverityError,
+						Path:     superblockPath(a.doc.File, j),
+						Network:  superA.String(),
+						Message:  fmt.Sprintf("superblock %s overlaps with superblock %s of %s", superA.String(), superB.String(), b.name),
+						Position: a.doc.SuperblockPosition(superA),
+					})
+				}
+			}
+		}
+	}
+	return overlaps
+}
+
+// superblockPath is the yaml path of the i-th of AllSuperblocks
+func superblockPath(file *atf.File, i int) string {
+	if file.Superblock != nil {
+		if i == 0 {
+			return "superBlock"
+		}
+		i--
+	}
+	return fmt.Sprintf("superBlocks[%d]", i)
+}
+
+// loadWorkspaceDocument loads a file of a workspace
+func loadWorkspaceDocument(name string) (*atf.Document, error) {
+	inFile, err := getInputFile(name)
+	if err != nil {
+		return nil, err
+	}
+	defer inFile.Close()
+	return loadAtfDocument(inFile)
+}
+
+// validateWorkspace validates every atf file below root and checks their
+// superblocks don't overlap
+func validateWorkspace(root string, rules []*atf.Rule) ([]*validateResult, error) {
+	names, err := findAtfFiles(root)
+	if err != nil {
+		return nil, err
+	}
+
+	findings := make(map[string][]*atf.Finding)
+	docs := make([]*workspaceDocument, 0, len(names))
+	for _, name := range names {
+		doc, err := loadWorkspaceDocument(name)
+		if err != nil {
+			findings[name] = errorFindings("load", err)
+			continue
+		}
+		findings[name] = validateDocument(doc, rules)
+		docs = append(docs, &workspaceDocument{name, doc})
+	}
+	for name, overlaps := range superblockOverlaps(docs) {
+		findings[name] = append(findings[name], overlaps...)
+	}
+
+	results := make([]*validateResult, 0, len(names))
+	for _, name := range names {
+		results = append(results, newValidateResult(name, findings[name]))
+	}
+	return results, nil
+}
+
+// renderWorkspace renders every atf file below root to the same relative
+// path below out, and an index of all superblocks
+func renderWorkspace(root string, out string, includeFree bool) error {
+	ext, ok := renderExtensions[*renderFormat]
+	if *renderFormat == "template" {
+		// my.html.tmpl renders to .html files
+		ext, ok = filepath.Ext(strings.TrimSuffix(*renderTemplateFile, filepath.Ext(*renderTemplateFile))), true
+		if ext == "" {
+			ext = ".txt"
+		}
+	}
+	if !ok {
+		return errors.Errorf("unknown render format '%s'", *renderFormat)
+	}
+
+	names, err := findAtfFiles(root)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return errors.Errorf("no atf files in %s", root)
+	}
+
+	docs := make([]*workspaceDocument, 0, len(names))
+	pools := make([]*netpool.ParsedATF, 0, len(names))
+	for _, name := range names {
+		doc, pool, err := loadFile(name)
+		if err != nil {
+			return &fileError{name: name, err: err}
+		}
+		docs = append(docs, &workspaceDocument{name, doc})
+		pools = append(pools, pool)
+	}
+	overlaps := superblockOverlaps(docs)
+	for _, doc := range docs {
+		if overlaps := overlaps[doc.name]; len(overlaps) > 0 {
+			return &fileError{name: doc.name, err: &atf.PositionError{Position: overlaps[0].Position, Err: errors.New(overlaps[0].Message)}}
+		}
+	}
+
+	indexName := workspaceIndexName(*renderFormat)
+	targets := make([]string, 0, len(names))
+	for _, name := range names {
+		rel, err := filepath.Rel(root, name)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(filepath.Dir(rel), atfBaseName(rel)+ext)
+		if target == indexName {
+			return &fileError{name: name, err: errors.Errorf("renders to %s, which is the index of the workspace", target)}
+		}
+		targets = append(targets, target)
+	}
+
+	entries := make([]*workspaceIndexEntry, 0, len(names))
+	for i, name := range names {
+		target := targets[i]
+		outBuffer := &bytes.Buffer{}
+		if err := renderPool(outBuffer, pools[i], includeFree); err != nil {
+			return &fileError{name: name, err: err}
+		}
+		if err := os.MkdirAll(filepath.Join(out, filepath.Dir(target)), 0777); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(out, target), outBuffer.Bytes(), 0666); err != nil {
+			return err
+		}
+
+		entry := &workspaceIndexEntry{
+			Name:  pools[i].File.Name,
+			File:  path.Clean(filepath.ToSlash(target)),
+			title: superblockTitle(pools[i].File),
+		}
+		for _, pool := range pools[i].Pools {
+			entry.Superblocks = append(entry.Superblocks, pool.Super().String())
+		}
+		entries = append(entries, entry)
+	}
+
+	index, err := workspaceIndex(*renderFormat, entries)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(out, indexName), index, 0666)
+}
+
+// workspaceIndexEntry is a rendered file listed in the workspace index
+type workspaceIndexEntry struct {
+	Superblocks []string `json:"superblocks"`
+	Name        *string  `json:"name,omitempty"`
+	File        string   `json:"file"`
+	title       string
+}
+
+// workspaceIndexName is the file name of the index for the render format,
+// formats without a list of their own get a markdown index
+func workspaceIndexName(format string) string {
+	switch format {
+	case "json", "csv", "tsv", "html":
+		return "index" + renderExtensions[format]
+	default:
+		return "index.md"
+	}
+}
+
+// workspaceIndex lists the rendered files in the format of workspaceIndexName
+func workspaceIndex(format string, entries []*workspaceIndexEntry) ([]byte, error) {
+	index := &bytes.Buffer{}
+	switch format {
+	case "json":
+		enc := json.NewEncoder(index)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(entries); err != nil {
+			return nil, err
+		}
+	case "csv", "tsv":
+		w := csv.NewWriter(index)
+		if format == "tsv" {
+			w.Comma = '\t'
+		}
+		w.Write([]string{"Superblocks", "Name", "File"})
+		for _, entry := range entries {
+			name := ""
+			if entry.Name != nil {
+				name = *entry.Name
+			}
+			w.Write([]string{strings.Join(entry.Superblocks, " "), name, entry.File})
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return nil, err
+		}
+	case "html":
+		fmt.Fprintf(index, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>Superblocks</title>\n</head>\n<body>\n")
+		fmt.Fprintf(index, "<!-- Generated by atfutil, DO NOT EDIT -->\n<h1>Superblocks</h1>\n<ul>\n")
+		for _, entry := range entries {
+			fmt.Fprintf(index, "<li><a href=\"%s\">%s</a></li>\n", html.EscapeString(entry.File), html.EscapeString(entry.title))
+		}
+		fmt.Fprintf(index, "</ul>\n</body>\n</html>\n")
+	default:
+		fmt.Fprintf(index, "# Superblocks\n\n")
+		fmt.Fprintf(index, "[//]: # (%s)\n\n", "Generated by atfutil, DO NOT EDIT")
+		for _, entry := range entries {
+			fmt.Fprintf(index, "- [%s](%s)\n", entry.title, entry.File)
+		}
+	}
+	return index.Bytes(), nil
+}
+
+// superblockTitle names a file by its superblocks and name
+func superblockTitle(file *atf.File) string {
+	supers := make([]string, 0, 1)
+	for _, super := range file.AllSuperblocks() {
+		supers = append(supers, super.String())
+	}
+	title := strings.Join(supers, ", ")
+	if file.Name != nil {
+		title += " " + *file.Name
+	}
+	return title
+}
@@ -0,0 +1,146 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package atfutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"atfutil/pkg/atf"
+)
+
+func TestAtfBaseName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"atf/10.99.0.0-16.atf.yaml", "10.99.0.0-16"},
+		{"prod.atf.yml", "prod"},
+		{"atf/.atf.yaml", ""},
+		{"atf/readme.yaml", ""},
+		{"atf.yaml", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := atfBaseName(tt.name); got != tt.want {
+				t.Errorf("atfBaseName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindAtfFiles(t *testing.T) {
+	root, err := ioutil.TempDir("", "workspace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	for _, name := range []string{"b.atf.yaml", "a/c.atf.yml", "a/notes.md", "a/d.yaml"} {
+		if err := os.MkdirAll(filepath.Join(root, filepath.Dir(name)), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(root, name), nil, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := findAtfFiles(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(root, "a/c.atf.yml"), filepath.Join(root, "b.atf.yaml")}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("findAtfFiles() = %v, want %v", files, want)
+	}
+}
+
+func TestSuperblockOverlaps(t *testing.T) {
+	parse := func(name string, text string) *workspaceDocument {
+		doc, err := atf.ParseDocument([]byte(text))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &workspaceDocument{name, doc}
+	}
+	docs := []*workspaceDocument{
+		parse("a", "superBlock: 10.20.0.0/16\nallocations: []\n"),
+		parse("b", "superBlock: 10.30.0.0/16\nallocations: []\n"),
+		parse("c", "superBlock: 10.40.0.0/16\nsuperBlocks:\n  - 10.20.128.0/17\nallocations: []\n"),
+		parse("d", "superBlock: 10.0.0.0/8\nallocations: []\n"),
+	}
+
+	overlaps := superblockOverlaps(docs)
+	if len(overlaps["a"]) != 0 || len(overlaps["b"]) != 0 {
+		t.Errorf("expected no overlaps for the first files, got %v %v", overlaps["a"], overlaps["b"])
+	}
+
+	if len(overlaps["c"]) != 1 {
+		t.Fatalf("expected one overlap for c, got %d", len(overlaps["c"]))
+	}
+	finding := overlaps["c"][0]
+	if finding.Path != "superBlocks[0]" || finding.Network != "10.20.128.0/17" || finding.Position != (atf.Position{Line: 3, Column: 5}) {
+		t.Errorf("unexpected finding %+v", finding)
+	}
+	if !strings.Contains(finding.Message, "10.20.0.0/16 of a") {
+		t.Errorf("unexpected message %q", finding.Message)
+	}
+
+	// d contains every other superblock
+	if len(overlaps["d"]) != 4 {
+		t.Errorf("expected 4 overlaps for d, got %d", len(overlaps["d"]))
+	}
+}
+
+func TestWorkspaceIndex(t *testing.T) {
+	name := "prod"
+	entries := []*workspaceIndexEntry{
+		{Superblocks: []string{"10.20.0.0/16", "172.22.0.0/20"}, Name: &name, File: "prod.md", title: "10.20.0.0/16, 172.22.0.0/20 prod"},
+	}
+	tests := []struct {
+		format string
+		index  string
+		want   string
+	}{
+		{"markdown", "index.md", "# Superblocks\n\n[//]: # (Generated by atfutil, DO NOT EDIT)\n\n- [10.20.0.0/16, 172.22.0.0/20 prod](prod.md)\n"},
+		{"svg", "index.md", "# Superblocks\n\n[//]: # (Generated by atfutil, DO NOT EDIT)\n\n- [10.20.0.0/16, 172.22.0.0/20 prod](prod.md)\n"},
+		{"csv", "index.csv", "Superblocks,Name,File\n10.20.0.0/16 172.22.0.0/20,prod,prod.md\n"},
+		{"json", "index.json", "[\n  {\n    \"superblocks\": [\n      \"10.20.0.0/16\",\n      \"172.22.0.0/20\"\n    ],\n    \"name\": \"prod\",\n    \"file\": \"prod.md\"\n  }\n]\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			if got := workspaceIndexName(tt.format); got != tt.index {
+				t.Errorf("workspaceIndexName() = %q, want %q", got, tt.index)
+			}
+			got, err := workspaceIndex(tt.format, entries)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("workspaceIndex() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package netcalc
+
+import (
+	"math/big"
+	"net"
+
+	"atfutil/pkg/cidr"
+)
+
+// AddressCount returns the number of addresses in the given network, unlike
+// cidr.AddressCount it does not overflow for large IPv6 networks
+func AddressCount(ipnet *net.IPNet) *big.Int {
+	prefixLen, bits := ipnet.Mask.Size()
+	return new(big.Int).Lsh(big.NewInt(1), uint(bits-prefixLen))
+}
+
+// UsableRange returns the first and last address of the given network that
+// can be assigned to a host. For IPv4 networks larger than /31 the network
+// and broadcast address are excluded.
+func UsableRange(ipnet *net.IPNet) (net.IP, net.IP) {
+	first, last := cidr.AddressRange(ipnet)
+	prefixLen, bits := ipnet.Mask.Size()
+	if bits == IPV4_MASK_BITS && prefixLen < IPV4_MASK_BITS-1 {
+		return cidr.Inc(first), cidr.Dec(last)
+	}
+	return first, last
+}
+
+// FreeBlocks returns the unallocated space of the pool as the largest
+// possible aligned blocks
+func (ipnp *IPNetPool) FreeBlocks() []*net.IPNet {
+	free := make([]*net.IPNet, 0)
+	for _, block := range ipnp.FindAllAllocations() {
+		if !block.Alloc {
+			free = append(free, block.Net)
+		}
+	}
+	return free
+}
+
+// Capacity returns how many networks with the given prefix length could still
+// be allocated from the pool
+func (ipnp *IPNetPool) Capacity(prefixLen int) *big.Int {
+	capacity := new(big.Int)
+	for _, free := range ipnp.FreeBlocks() {
+		freeLen, _ := free.Mask.Size()
+		if freeLen > prefixLen {
+			continue
+		}
+		capacity.Add(capacity, new(big.Int).Lsh(big.NewInt(1), uint(prefixLen-freeLen)))
+	}
+	return capacity
+}
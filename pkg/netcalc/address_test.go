@@ -0,0 +1,101 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package netcalc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAddressCount(t *testing.T) {
+	tests := []struct {
+		cidr string
+		want string
+	}{
+		{"10.42.0.0/24", "256"},
+		{"10.42.0.1/32", "1"},
+		{"fd00:1234::/64", "18446744073709551616"},
+		{"fd00:1234::/48", "1208925819614629174706176"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cidr, func(t *testing.T) {
+			if got := AddressCount(CIDR(tt.cidr)).String(); got != tt.want {
+				t.Errorf("AddressCount() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUsableRange(t *testing.T) {
+	tests := []struct {
+		cidr  string
+		first string
+		last  string
+	}{
+		{"10.42.0.0/24", "10.42.0.1", "10.42.0.254"},
+		{"10.42.0.0/30", "10.42.0.1", "10.42.0.2"},
+		{"10.42.0.0/31", "10.42.0.0", "10.42.0.1"},
+		{"10.42.0.1/32", "10.42.0.1", "10.42.0.1"},
+		{"fd00:1234::/64", "fd00:1234::", "fd00:1234::ffff:ffff:ffff:ffff"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cidr, func(t *testing.T) {
+			first, last := UsableRange(CIDR(tt.cidr))
+			if first.String() != tt.first || last.String() != tt.last {
+				t.Errorf("UsableRange() = %s - %s, want %s - %s", first, last, tt.first, tt.last)
+			}
+		})
+	}
+}
+
+func TestIPNetPool_Capacity(t *testing.T) {
+	pool, err := NewIPNetPool("10.42.0.0/23",
+		CIDR("10.42.0.0/26"),
+		CIDR("10.42.1.0/25"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	free := make([]string, 0)
+	for _, block := range pool.FreeBlocks() {
+		free = append(free, block.String())
+	}
+	if want := []string{"10.42.0.64/26", "10.42.0.128/25", "10.42.1.128/25"}; !reflect.DeepEqual(free, want) {
+		t.Errorf("FreeBlocks() = %v, want %v", free, want)
+	}
+
+	tests := []struct {
+		prefixLen int
+		want      string
+	}{
+		{24, "0"},
+		{25, "2"},
+		{26, "5"},
+		{28, "20"},
+	}
+	for _, tt := range tests {
+		if got := pool.Capacity(tt.prefixLen).String(); got != tt.want {
+			t.Errorf("Capacity(%d) = %s, want %s", tt.prefixLen, got, tt.want)
+		}
+	}
+}
@@ -22,7 +22,9 @@
 package netcalc
 
 import (
-	"encoding/binary"
+	"bytes"
+	"fmt"
+	"math/big"
 	"net"
 	"sort"
 
@@ -31,8 +33,15 @@ import (
 	"github.com/pkg/errors"
 )
 
-const AWS_MIN_SUBNET_SIZE = 28
-const MASK_BITS = 32
+const (
+	IPV4_MASK_BITS = 32
+	IPV6_MASK_BITS = 128
+
+	// IPV4_MIN_SUBNET_SIZE is the longest prefix AWS accepts for a subnet (Azure goes down to /29)
+	IPV4_MIN_SUBNET_SIZE = 28
+	// IPV6_MIN_SUBNET_SIZE is the only IPv6 subnet prefix both Azure and AWS accept
+	IPV6_MIN_SUBNET_SIZE = 64
+)
 
 var (
 	// ErrRootNotNetworkAddr indicates the given root address is not a network address of given CIDR
@@ -40,8 +49,36 @@ var (
 
 	// ErrAllocationOutOfBounds indicates at least one of the given allocations is out of bounds of root network
 	ErrAllocationOutOfBounds = errors.New("netcalc: given allocations out of bounds of root network")
+
+	// ErrNotAllocated indicates the given network is not an allocation in the pool
+	ErrNotAllocated = errors.New("netcalc: given network is not allocated in pool")
+
+	// ErrFamilyMismatch indicates an allocation is not of the same address family as the root network
+	ErrFamilyMismatch = errors.New("netcalc: given allocation is not of the same address family as root network")
 )
 
+// MaskBits returns the number of bits in an address of the given network's family
+func MaskBits(ipnet *net.IPNet) int {
+	if ipnet.IP.To4() != nil {
+		return IPV4_MASK_BITS
+	}
+	return IPV6_MASK_BITS
+}
+
+// MinSubnetSize returns the longest prefix length cloud providers accept
+// for a subnet in the given network's family
+func MinSubnetSize(ipnet *net.IPNet) int {
+	if ipnet.IP.To4() != nil {
+		return IPV4_MIN_SUBNET_SIZE
+	}
+	return IPV6_MIN_SUBNET_SIZE
+}
+
+// SameFamily reports whether both networks are IPv4 or both are IPv6
+func SameFamily(a, b *net.IPNet) bool {
+	return (a.IP.To4() != nil) == (b.IP.To4() != nil)
+}
+
 // IPNetPool is a list of allocations in one larger superblock
 type IPNetPool struct {
 	super *net.IPNet
@@ -62,7 +99,7 @@ func (ipnp *IPNetPool) Len() int {
 // Less reports whether the element with
 // index i should sort before the element with index j.
 func (ipnp *IPNetPool) Less(i, j int) bool {
-	return binary.BigEndian.Uint32(ipnp.alloc[i].IP) < binary.BigEndian.Uint32(ipnp.alloc[j].IP)
+	return bytes.Compare(ipnp.alloc[i].IP.To16(), ipnp.alloc[j].IP.To16()) < 0
 }
 
 // Swap swaps the elements with indexes i and j.
@@ -72,33 +109,28 @@ func (ipnp *IPNetPool) Swap(i, j int) {
 	ipnp.alloc[j] = jPtr
 }
 
-func (ipnp *IPNetPool) Alloc(requestedSize int) (*net.IPNet, error) {
-	allocBlockAt := -1
-	currentSize := -1
-	blocks := ipnp.FindAllAllocations()
+// Super returns the superblock of the pool
+func (ipnp *IPNetPool) Super() *net.IPNet {
+	return ipnp.super
+}
 
-	for i, block := range blocks {
-		if block.Alloc {
-			continue
-		}
-		blockSize, _ := block.Net.Mask.Size()
-		if allocBlockAt == -1 && blockSize <= requestedSize {
-			allocBlockAt = i
-			currentSize = blockSize
-			continue
-		}
+// Alloc allocates a network with the given prefix length from the smallest
+// free block it fits into
+func (ipnp *IPNetPool) Alloc(requestedSize int) (*net.IPNet, error) {
+	return ipnp.AllocWith(requestedSize, BestFit{})
+}
 
-		if blockSize <= requestedSize && blockSize > currentSize {
-			allocBlockAt = i
-			currentSize = blockSize
-		}
+// AllocWith allocates a network with the given prefix length at the place
+// the strategy picks
+func (ipnp *IPNetPool) AllocWith(requestedSize int, strategy Strategy) (*net.IPNet, error) {
+	if requestedSize > MaskBits(ipnp.super) {
+		return nil, errors.Errorf("requested prefix length /%d is longer than the address family allows", requestedSize)
 	}
-	if allocBlockAt == -1 {
+
+	ipnet := strategy.Pick(ipnp.FindAllAllocations(), requestedSize)
+	if ipnet == nil {
 		return nil, errors.New("no space to allocate a subnet of the requested size")
 	}
-	ipnet := blocks[allocBlockAt].Net
-	// downsize the network
-	ipnet.Mask = net.CIDRMask(requestedSize, MASK_BITS)
 	ipnp.alloc = append(ipnp.alloc, ipnet)
 
 	err := ipnp.fixAndVerifyInternalState()
@@ -109,86 +141,143 @@ func (ipnp *IPNetPool) Alloc(requestedSize int) (*net.IPNet, error) {
 	return ipnet, nil
 }
 
-// FindAllAllocations calculates all Blocks in the IPNetPool. Unallocated
-// Space is reduced to the largest allocatable blocks.
-func (ipnp *IPNetPool) FindAllAllocations() []*Block {
-	blocks := make([]*Block, 0, MASK_BITS)
-
-	// this is the IP we're currently trying to find a matching free or allocated
-	// network for
-	var currentIP net.IP
-	currentIP = ipnp.super.IP
-
-	// because allocations are sorted we only ever need to match against the
-	// next allocation, this is the index that allocation.
-	allocIndex := 0
-	var alloc *net.IPNet
-
-	// we build a reusable slice of the allocations and one entry for our
-	// network to see if it fits in.
-	checkSlc := append(ipnp.alloc, nil)
-	checkSliWorkIndex := len(checkSlc) - 1
-
-mainloop:
-	for {
-		// a logic error might leave us never moving forward, detect this
-		var didIterate bool
-
-		// iterate over all possible subnet sizes our current IP can support,
-		// largest network (lowest number) to smallest network (highest number)
-		for _, netSize := range LegalNetworkSizes(currentIP, ipnp.super.Mask) {
-			// have we found all allocated blocks?
-			if len(ipnp.alloc) > allocIndex {
-				alloc = ipnp.alloc[allocIndex]
-			} else {
-				alloc = nil
-			}
-			// populate our working pointer
-			checkSlc[checkSliWorkIndex] = &net.IPNet{
-				IP:   currentIP,
-				Mask: netSize,
-			}
+// AllocFrom allocates a network with the given prefix length from whichever
+// of the pools the strategy picks, all of them are treated as one address
+// space ordered by address, IPv4 before IPv6. The pool the network was
+// allocated from is returned with it.
+func AllocFrom(pools []*IPNetPool, requestedSize int, strategy Strategy) (*IPNetPool, *net.IPNet, error) {
+	blocks := make([]*Block, 0)
+	for _, pool := range pools {
+		if requestedSize <= MaskBits(pool.super) {
+			blocks = append(blocks, pool.FindAllAllocations()...)
+		}
+	}
+	// strategies expect the blocks in address order, the pools may be in any
+	sort.SliceStable(blocks, func(i, j int) bool {
+		iv4, jv4 := blocks[i].Net.IP.To4() != nil, blocks[j].Net.IP.To4() != nil
+		if iv4 != jv4 {
+			return iv4
+		}
+		return bytes.Compare(blocks[i].Net.IP.To16(), blocks[j].Net.IP.To16()) < 0
+	})
+	ipnet := strategy.Pick(blocks, requestedSize)
+	if ipnet == nil {
+		return nil, nil, errors.New("no space to allocate a subnet of the requested size")
+	}
+	for _, pool := range pools {
+		if SameFamily(pool.super, ipnet) && pool.super.Contains(ipnet.IP) {
+			return pool, ipnet, pool.Claim(ipnet)
+		}
+	}
+	return nil, nil, errors.Errorf("strategy picked %s outside of all pools", ipnet.String())
+}
 
-			// check if the current net is the allocation
-			if alloc != nil && alloc.IP.Equal(currentIP) && alloc.Mask.String() == netSize.String() {
-				blocks = append(blocks, &Block{
-					Net:   alloc,
-					Alloc: true,
-				})
-				allocIndex++
-				prefixLen, _ := alloc.Mask.Size()
-				nextNet, _ := cidr.NextSubnet(checkSlc[checkSliWorkIndex], prefixLen)
-				currentIP = nextNet.IP
-				didIterate = true
-				break
-			}
+// OverlapError is returned by Claim when the requested network overlaps an
+// existing allocation
+type OverlapError struct {
+	Network  *net.IPNet
+	Conflict *net.IPNet
+}
 
-			// check if the current net does not overlap with any other net
-			if err := cidr.VerifyNoOverlap(checkSlc, ipnp.super); err == nil {
-				// found a free block
-				blocks = append(blocks, &Block{
-					Net:   checkSlc[checkSliWorkIndex],
-					Alloc: false,
-				})
-				prefixLen, _ := checkSlc[checkSliWorkIndex].Mask.Size()
-				nextNet, _ := cidr.NextSubnet(checkSlc[checkSliWorkIndex], prefixLen)
-				currentIP = nextNet.IP
-				didIterate = true
-				break
+func (e *OverlapError) Error() string {
+	return fmt.Sprintf("netcalc: %s overlaps with allocation %s", e.Network.String(), e.Conflict.String())
+}
+
+// Claim allocates exactly the given network, it has to be free and inside
+// the superblock
+func (ipnp *IPNetPool) Claim(ipnet *net.IPNet) error {
+	if err := checkAllocation(ipnp.super, ipnet); err != nil {
+		return err
+	}
+	if err := cidr.VerifyNoOverlap([]*net.IPNet{ipnet}, ipnp.super); err != nil {
+		return errors.Wrap(ErrAllocationOutOfBounds, ipnet.String())
+	}
+
+	checkSlc := make([]*net.IPNet, 0, len(ipnp.alloc)+1)
+	checkSlc = append(checkSlc, ipnp.alloc...)
+	checkSlc = append(checkSlc, ipnet)
+	if err := cidr.VerifyNoOverlap(checkSlc, ipnp.super); err != nil {
+		for _, alloc := range ipnp.alloc {
+			if alloc.Contains(ipnet.IP) || ipnet.Contains(alloc.IP) {
+				return &OverlapError{Network: ipnet, Conflict: alloc}
 			}
 		}
-		// break out if we stepped out of bounds
-		if !ipnp.super.Contains(currentIP) {
-			break mainloop
-		}
-		if !didIterate {
-			panic("error in logic, panicing to avoid endless loop")
+		return err
+	}
+
+	ipnp.alloc = checkSlc
+	return ipnp.fixAndVerifyInternalState()
+}
+
+// Release frees the given allocation, it has to match an allocation exactly
+func (ipnp *IPNetPool) Release(ipnet *net.IPNet) error {
+	for i, alloc := range ipnp.alloc {
+		if alloc.IP.Equal(ipnet.IP) && alloc.Mask.String() == ipnet.Mask.String() {
+			ipnp.alloc = append(ipnp.alloc[:i], ipnp.alloc[i+1:]...)
+			return nil
 		}
 	}
+	return errors.Wrap(ErrNotAllocated, ipnet.String())
+}
 
+// FindAllAllocations calculates all Blocks in the IPNetPool. Unallocated
+// Space is reduced to the largest allocatable blocks.
+func (ipnp *IPNetPool) FindAllAllocations() []*Block {
+	blocks := make([]*Block, 0, 2*len(ipnp.alloc)+1)
+
+	// allocations are sorted, so walking them once and filling the gaps in
+	// between with free blocks covers the whole superblock
+	current := ipToInt(ipnp.super.IP)
+	for _, alloc := range ipnp.alloc {
+		start := ipToInt(alloc.IP)
+		blocks = ipnp.appendFree(blocks, current, start)
+		blocks = append(blocks, &Block{
+			Net:   alloc,
+			Alloc: true,
+		})
+		current = start.Add(start, AddressCount(alloc))
+	}
+	end := ipToInt(ipnp.super.IP)
+	end.Add(end, AddressCount(ipnp.super))
+	return ipnp.appendFree(blocks, current, end)
+}
+
+// appendFree appends the largest aligned blocks covering the addresses from
+// start up to (excluding) end, none of them spans the entire superblock
+func (ipnp *IPNetPool) appendFree(blocks []*Block, start *big.Int, end *big.Int) []*Block {
+	superLen, bits := ipnp.super.Mask.Size()
+	current := new(big.Int).Set(start)
+	for current.Cmp(end) < 0 {
+		for prefixLen := superLen + 1; prefixLen <= bits; prefixLen++ {
+			size := new(big.Int).Lsh(big.NewInt(1), uint(bits-prefixLen))
+			next := new(big.Int).Add(current, size)
+			if new(big.Int).Mod(current, size).Sign() != 0 || next.Cmp(end) > 0 {
+				continue
+			}
+			blocks = append(blocks, &Block{
+				Net:   &net.IPNet{IP: intToIP(current, bits), Mask: net.CIDRMask(prefixLen, bits)},
+				Alloc: false,
+			})
+			current = next
+			break
+		}
+	}
 	return blocks
 }
 
+// ipToInt returns the address as a number, IPv4 addresses use 32 bits
+func ipToInt(ip net.IP) *big.Int {
+	if ip4 := ip.To4(); ip4 != nil {
+		return new(big.Int).SetBytes(ip4)
+	}
+	return new(big.Int).SetBytes(ip.To16())
+}
+
+// intToIP returns the address of the given family for a number
+func intToIP(ipInt *big.Int, bits int) net.IP {
+	return net.IP(ipInt.FillBytes(make([]byte, bits/8)))
+}
+
 func (ipnp *IPNetPool) fixAndVerifyInternalState() error {
 	sort.Sort(ipnp)
 
@@ -199,6 +288,24 @@ func (ipnp *IPNetPool) fixAndVerifyInternalState() error {
 	return nil
 }
 
+// checkAllocation verifies a single allocation is usable in the given superblock,
+// overlaps are left to cidr.VerifyNoOverlap
+func checkAllocation(super *net.IPNet, allocation *net.IPNet) error {
+	if !SameFamily(super, allocation) {
+		return errors.Wrapf(ErrFamilyMismatch, "%s in %s", allocation.String(), super.String())
+	}
+	// FindAllAllocations only looks at blocks smaller than the superblock
+	if allocation.String() == super.String() {
+		return errors.Errorf("allocation %s spans the entire superblock", allocation.String())
+	}
+	ip := allocation.IP
+	ipMasked := allocation.IP.Mask(allocation.Mask)
+	if !ip.Equal(ipMasked) {
+		return errors.Errorf("used non-set IP %s (did you mean %s?)", ip.String(), ipMasked.String())
+	}
+	return nil
+}
+
 // NewIPNetPool creates a new pool of IP allocation blocks within one superblock
 func NewIPNetPool(superCidr string, allocations ...*net.IPNet) (*IPNetPool, error) {
 	ip, super, err := net.ParseCIDR(superCidr)
@@ -210,10 +317,8 @@ func NewIPNetPool(superCidr string, allocations ...*net.IPNet) (*IPNetPool, erro
 	}
 
 	for _, allocation := range allocations {
-		ip := allocation.IP
-		ipMasked := allocation.IP.Mask(allocation.Mask)
-		if !ip.Equal(ipMasked) {
-			return nil, errors.Errorf("used non-set IP %s (did you mean %s?)", ip.String(), ipMasked.String())
+		if err := checkAllocation(super, allocation); err != nil {
+			return nil, err
 		}
 	}
 
@@ -227,11 +332,13 @@ func NewIPNetPool(superCidr string, allocations ...*net.IPNet) (*IPNetPool, erro
 	return ipnp, nil
 }
 
+// LegalNetworkSizes returns all masks longer than min that ip is a valid
+// network address for, the address family is taken from min
 func LegalNetworkSizes(ip net.IP, min net.IPMask) []net.IPMask {
 	masks := make([]net.IPMask, 0, 1)
-	minMask, _ := min.Size()
-	for i := minMask + 1; i <= MASK_BITS; i++ {
-		mask := net.CIDRMask(i, MASK_BITS)
+	minMask, bits := min.Size()
+	for i := minMask + 1; i <= bits; i++ {
+		mask := net.CIDRMask(i, bits)
 		if ip.Equal(ip.Mask(mask)) {
 			masks = append(masks, mask)
 		}
@@ -320,3 +320,204 @@ func TestIPNetPool_AllocLargerThanSupper(t *testing.T) {
 		t.Fatal("should have failed to allocate net larger than superblock")
 	}
 }
+
+func TestLegalNetworkSizesIPv6(t *testing.T) {
+	got := LegalNetworkSizes(net.ParseIP("fd00:1234:0:ff00::"), net.CIDRMask(48, 128))
+	want := []net.IPMask{}
+	for i := 56; i <= 128; i++ {
+		want = append(want, net.CIDRMask(i, 128))
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LegalNetworkSizes() = %v, want %v", got, want)
+	}
+}
+
+func TestAllocationsIPv6(t *testing.T) {
+	pool, err := NewIPNetPool("fd00:1234::/48",
+		CIDR("fd00:1234::/50"),
+		CIDR("fd00:1234:0:8000::/49"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks := pool.FindAllAllocations()
+
+	expectedBlocks := []*Block{
+		{Alloc: true, Net: CIDR("fd00:1234::/50")},
+		{Alloc: false, Net: CIDR("fd00:1234:0:4000::/50")},
+		{Alloc: true, Net: CIDR("fd00:1234:0:8000::/49")},
+	}
+	if !reflect.DeepEqual(blocks, expectedBlocks) {
+		t.Log("blocks aren't as expected")
+		t.Log("expected:")
+		PrintBlocks(t, expectedBlocks)
+		t.Log("actual: ")
+		PrintBlocks(t, blocks)
+		t.Fail()
+	}
+}
+
+func TestIPNetPool_AllocIPv6(t *testing.T) {
+	pool, err := NewIPNetPool("fd00:1234::/48",
+		CIDR("fd00:1234::/56"),
+		CIDR("fd00:1234:0:100::/64"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	net, err := pool.Alloc(64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedNet := "fd00:1234:0:101::/64"
+	if net.String() != expectedNet {
+		t.Fatalf("expected net %s, got %s", expectedNet, net.String())
+	}
+
+	net, err = pool.Alloc(56)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedNet = "fd00:1234:0:200::/56"
+	if net.String() != expectedNet {
+		t.Fatalf("expected net %s, got %s", expectedNet, net.String())
+	}
+}
+
+func TestAllocationsIPv6Many(t *testing.T) {
+	// every other /64 of the first 600 is allocated
+	allocations := make([]*net.IPNet, 0, 300)
+	for i := 0; i < 600; i += 2 {
+		allocations = append(allocations, CIDR(fmt.Sprintf("fd00:12:0:%x::/64", i)))
+	}
+	pool, err := NewIPNetPool("fd00:12::/40", allocations...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blocks := pool.FindAllAllocations()
+	allocated := 0
+	for i, block := range blocks {
+		if block.Alloc {
+			allocated++
+			continue
+		}
+		if i < 600 && block.Net.String() != fmt.Sprintf("fd00:12:0:%x::/64", i) {
+			t.Fatalf("expected the free /64 after an allocation at %d, got %s", i, block.Net.String())
+		}
+	}
+	if allocated != 300 {
+		t.Fatalf("got %d allocated blocks, expected 300", allocated)
+	}
+	// the rest of the /40 is covered by ever larger free blocks up to a /41
+	if last := blocks[len(blocks)-1].Net.String(); last != "fd00:12:80::/41" {
+		t.Fatalf("expected the last block to be fd00:12:80::/41, got %s", last)
+	}
+
+	ipnet, err := pool.Alloc(64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ipnet.String() != "fd00:12:0:1::/64" {
+		t.Fatalf("expected net fd00:12:0:1::/64, got %s", ipnet.String())
+	}
+}
+
+func TestAllocationMixedFamilies(t *testing.T) {
+	_, err := NewIPNetPool("10.42.0.0/16",
+		CIDR("fd00:1234::/64"),
+	)
+	if err == nil {
+		t.Fatal("should have errored")
+	}
+}
+
+func TestMinSubnetSize(t *testing.T) {
+	if size := MinSubnetSize(CIDR("10.42.0.0/16")); size != IPV4_MIN_SUBNET_SIZE {
+		t.Errorf("expected %d for IPv4, got %d", IPV4_MIN_SUBNET_SIZE, size)
+	}
+	if size := MinSubnetSize(CIDR("fd00:1234::/48")); size != IPV6_MIN_SUBNET_SIZE {
+		t.Errorf("expected %d for IPv6, got %d", IPV6_MIN_SUBNET_SIZE, size)
+	}
+}
+
+func TestAllocationEntireSuperblock(t *testing.T) {
+	_, err := NewIPNetPool("10.42.0.0/24",
+		CIDR("10.42.0.0/24"),
+	)
+	if err == nil {
+		t.Fatal("should have errored")
+	}
+}
+
+func TestIPNetPool_Release(t *testing.T) {
+	pool, err := NewIPNetPool("10.42.0.0/24",
+		CIDR("10.42.0.0/26"),
+		CIDR("10.42.0.64/26"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pool.Release(CIDR("10.42.0.0/27")); err == nil {
+		t.Fatal("should not release a network that is only part of an allocation")
+	}
+	if err := pool.Release(CIDR("10.42.0.0/26")); err != nil {
+		t.Fatal(err)
+	}
+	if err := pool.Release(CIDR("10.42.0.0/26")); err == nil {
+		t.Fatal("should not release a network twice")
+	}
+
+	net, err := pool.Alloc(26)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedNet := "10.42.0.0/26"
+	if net.String() != expectedNet {
+		t.Fatalf("expected net %s, got %s", expectedNet, net.String())
+	}
+}
+
+func TestIPNetPool_Claim(t *testing.T) {
+	pool, err := NewIPNetPool("10.42.0.0/16",
+		CIDR("10.42.0.0/24"),
+		CIDR("10.42.4.0/22"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pool.Claim(CIDR("10.42.1.0/24")); err != nil {
+		t.Fatal(err)
+	}
+
+	err = pool.Claim(CIDR("10.42.6.0/24"))
+	overlapErr, ok := err.(*OverlapError)
+	if !ok {
+		t.Fatalf("expected an OverlapError, got %v", err)
+	}
+	if overlapErr.Conflict.String() != "10.42.4.0/22" {
+		t.Fatalf("expected conflict with 10.42.4.0/22, got %s", overlapErr.Conflict.String())
+	}
+
+	if err := pool.Claim(CIDR("10.42.0.0/23")); err == nil {
+		t.Fatal("should not claim a network containing an allocation")
+	}
+	if err := pool.Claim(CIDR("10.43.0.0/24")); err == nil {
+		t.Fatal("should not claim a network outside the superblock")
+	}
+	if err := pool.Claim(CIDR("fd00:1234::/64")); err == nil {
+		t.Fatal("should not claim a network of another family")
+	}
+
+	net, err := pool.Alloc(24)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedNet := "10.42.2.0/24"
+	if net.String() != expectedNet {
+		t.Fatalf("expected net %s, got %s", expectedNet, net.String())
+	}
+}
@@ -0,0 +1,95 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package netcalc
+
+import (
+	"math/big"
+	"net"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Provider is the cloud provider a network is used with, providers keep some
+// addresses of every subnet for themselves
+type Provider string
+
+const (
+	ProviderNone  Provider = "none"
+	ProviderAzure Provider = "azure"
+	ProviderAWS   Provider = "aws"
+)
+
+// providerReserved is the number of addresses Azure and AWS reserve in every
+// subnet, the first four and the last one
+const providerReserved = 5
+
+// ProviderNames lists the names ProviderByName understands
+var ProviderNames = []string{string(ProviderAzure), string(ProviderAWS), string(ProviderNone)}
+
+// ProviderByName returns the provider with the given name, an empty name is
+// ProviderNone
+func ProviderByName(name string) (Provider, error) {
+	switch Provider(name) {
+	case "", ProviderNone:
+		return ProviderNone, nil
+	case ProviderAzure, ProviderAWS:
+		return Provider(name), nil
+	}
+	return "", errors.Errorf("unknown provider '%s' (known: %s)", name, strings.Join(ProviderNames, ", "))
+}
+
+// UsableHosts returns how many addresses of the network can be assigned to
+// hosts. Without provider these are the addresses in UsableRange.
+func UsableHosts(ipnet *net.IPNet, provider Provider) *big.Int {
+	count := AddressCount(ipnet)
+	reserved := 0
+	if provider == ProviderAzure || provider == ProviderAWS {
+		reserved = providerReserved
+	} else if prefixLen, bits := ipnet.Mask.Size(); bits == IPV4_MASK_BITS && prefixLen < IPV4_MASK_BITS-1 {
+		// network and broadcast address
+		reserved = 2
+	}
+	count.Sub(count, big.NewInt(int64(reserved)))
+	if count.Sign() < 0 {
+		count.SetInt64(0)
+	}
+	return count
+}
+
+// PrefixLenForHosts returns the longest prefix length of the family with the
+// given number of mask bits that has at least hosts usable addresses, -1 if
+// not even the whole address space has
+func PrefixLenForHosts(hosts int, bits int, provider Provider) int {
+	ip := net.IPv6zero
+	if bits == IPV4_MASK_BITS {
+		ip = net.IPv4zero.To4()
+	}
+	want := big.NewInt(int64(hosts))
+	for prefixLen := bits; prefixLen >= 0; prefixLen-- {
+		ipnet := &net.IPNet{IP: ip, Mask: net.CIDRMask(prefixLen, bits)}
+		if UsableHosts(ipnet, provider).Cmp(want) >= 0 {
+			return prefixLen
+		}
+	}
+	return -1
+}
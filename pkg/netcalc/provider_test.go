@@ -0,0 +1,86 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package netcalc
+
+import (
+	"testing"
+)
+
+func TestUsableHosts(t *testing.T) {
+	tests := []struct {
+		cidr     string
+		provider Provider
+		want     string
+	}{
+		{"10.42.0.0/24", ProviderNone, "254"},
+		{"10.42.0.0/24", ProviderAzure, "251"},
+		{"10.42.0.0/28", ProviderAWS, "11"},
+		{"10.42.0.0/31", ProviderNone, "2"},
+		{"10.42.0.0/30", ProviderAzure, "0"},
+		{"fd00:1234::/64", ProviderNone, "18446744073709551616"},
+		{"fd00:1234::/64", ProviderAWS, "18446744073709551611"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cidr+" "+string(tt.provider), func(t *testing.T) {
+			if got := UsableHosts(CIDR(tt.cidr), tt.provider).String(); got != tt.want {
+				t.Errorf("UsableHosts() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrefixLenForHosts(t *testing.T) {
+	tests := []struct {
+		name     string
+		hosts    int
+		bits     int
+		provider Provider
+		want     int
+	}{
+		{"200 hosts", 200, 32, ProviderNone, 24},
+		{"254 hosts", 254, 32, ProviderNone, 24},
+		{"255 hosts", 255, 32, ProviderNone, 23},
+		{"200 hosts azure", 200, 32, ProviderAzure, 24},
+		{"252 hosts azure", 252, 32, ProviderAzure, 23},
+		{"1 host", 1, 32, ProviderNone, 32},
+		{"1 host aws", 1, 32, ProviderAWS, 29},
+		{"ipv6", 1000, 128, ProviderAzure, 118},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PrefixLenForHosts(tt.hosts, tt.bits, tt.provider); got != tt.want {
+				t.Errorf("PrefixLenForHosts() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProviderByName(t *testing.T) {
+	for name, want := range map[string]Provider{"": ProviderNone, "none": ProviderNone, "azure": ProviderAzure, "aws": ProviderAWS} {
+		if got, err := ProviderByName(name); err != nil || got != want {
+			t.Errorf("ProviderByName(%q) = %q, %v, want %q", name, got, err, want)
+		}
+	}
+	if _, err := ProviderByName("gcp"); err == nil {
+		t.Error("ProviderByName(\"gcp\") succeeded, want an error")
+	}
+}
@@ -0,0 +1,149 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package netcalc
+
+import (
+	"net"
+	"strconv"
+	"strings"
+
+	"atfutil/pkg/cidr"
+
+	"github.com/pkg/errors"
+)
+
+// Strategy decides where in a pool a new network is allocated
+type Strategy interface {
+	// Pick returns the network with the requested prefix length to allocate
+	// given all blocks of a pool, or nil if there is no space for it.
+	Pick(blocks []*Block, requestedSize int) *net.IPNet
+}
+
+// BestFit allocates from the smallest free block the network fits into to
+// keep fragmentation low, this is the default strategy
+type BestFit struct{}
+
+// FirstFit allocates from the lowest free block the network fits into
+type FirstFit struct{}
+
+// TopDown allocates from the highest free block the network fits into,
+// starting at the top of that block
+type TopDown struct{}
+
+// Aligned allocates like BestFit, but only from free blocks that are at least
+// GrowBits larger than the requested network, so the allocation starts on the
+// boundary of that larger network. The rest of the block is not kept free.
+type Aligned struct {
+	GrowBits int
+}
+
+const DefaultStrategyName = "best-fit"
+
+// StrategyNames lists the names StrategyByName understands
+var StrategyNames = []string{"best-fit", "first-fit", "top-down", "aligned[:bits]"}
+
+// StrategyByName returns the strategy with the given name, an empty name
+// is the default strategy
+func StrategyByName(name string) (Strategy, error) {
+	switch name {
+	case "", DefaultStrategyName:
+		return BestFit{}, nil
+	case "first-fit":
+		return FirstFit{}, nil
+	case "top-down":
+		return TopDown{}, nil
+	case "aligned":
+		return Aligned{GrowBits: 1}, nil
+	}
+	if bitsStr, ok := strings.CutPrefix(name, "aligned:"); ok {
+		bits, err := strconv.Atoi(bitsStr)
+		if err != nil || bits < 1 {
+			return nil, errors.Errorf("invalid alignment '%s', expected a positive number of bits", bitsStr)
+		}
+		return Aligned{GrowBits: bits}, nil
+	}
+	return nil, errors.Errorf("unknown allocation strategy '%s' (known: %s)", name, strings.Join(StrategyNames, ", "))
+}
+
+func (BestFit) Pick(blocks []*Block, requestedSize int) *net.IPNet {
+	return pickSmallest(blocks, requestedSize, requestedSize)
+}
+
+func (FirstFit) Pick(blocks []*Block, requestedSize int) *net.IPNet {
+	for _, block := range blocks {
+		if fits(block, requestedSize) {
+			return firstSubnet(block.Net, requestedSize)
+		}
+	}
+	return nil
+}
+
+func (TopDown) Pick(blocks []*Block, requestedSize int) *net.IPNet {
+	for i := len(blocks) - 1; i >= 0; i-- {
+		if fits(blocks[i], requestedSize) {
+			_, last := cidr.AddressRange(blocks[i].Net)
+			mask := net.CIDRMask(requestedSize, MaskBits(blocks[i].Net))
+			return &net.IPNet{IP: last.Mask(mask), Mask: mask}
+		}
+	}
+	return nil
+}
+
+func (a Aligned) Pick(blocks []*Block, requestedSize int) *net.IPNet {
+	return pickSmallest(blocks, requestedSize-a.GrowBits, requestedSize)
+}
+
+// pickSmallest returns the start of the first of the smallest free blocks
+// with at most prefix length blockSize
+func pickSmallest(blocks []*Block, blockSize int, requestedSize int) *net.IPNet {
+	var best *Block
+	bestSize := -1
+	for _, block := range blocks {
+		if !fits(block, blockSize) {
+			continue
+		}
+		size, _ := block.Net.Mask.Size()
+		if size > bestSize {
+			best = block
+			bestSize = size
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	return firstSubnet(best.Net, requestedSize)
+}
+
+func fits(block *Block, requestedSize int) bool {
+	if block.Alloc {
+		return false
+	}
+	size, _ := block.Net.Mask.Size()
+	return size <= requestedSize
+}
+
+func firstSubnet(block *net.IPNet, requestedSize int) *net.IPNet {
+	return &net.IPNet{
+		IP:   block.IP,
+		Mask: net.CIDRMask(requestedSize, MaskBits(block)),
+	}
+}
@@ -0,0 +1,155 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package netcalc
+
+import (
+	"net"
+	"testing"
+)
+
+func TestIPNetPool_AllocWith(t *testing.T) {
+	tests := []struct {
+		strategy string
+		want     []string
+	}{
+		{"best-fit", []string{"10.42.0.64/27", "10.42.0.96/27"}},
+		{"first-fit", []string{"10.42.0.64/27", "10.42.0.96/27"}},
+		{"top-down", []string{"10.42.1.224/27", "10.42.1.192/27"}},
+		{"aligned", []string{"10.42.0.64/27", "10.42.0.128/27"}},
+		{"aligned:2", []string{"10.42.0.128/27", "10.42.1.128/27"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			pool, err := NewIPNetPool("10.42.0.0/23",
+				CIDR("10.42.0.0/26"),
+				CIDR("10.42.1.0/25"),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			strategy, err := StrategyByName(tt.strategy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, expectedNet := range tt.want {
+				net, err := pool.AllocWith(27, strategy)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if net.String() != expectedNet {
+					t.Fatalf("expected net %s, got %s", expectedNet, net.String())
+				}
+			}
+		})
+	}
+}
+
+func TestStrategyByName(t *testing.T) {
+	for _, name := range []string{"", "best-fit", "first-fit", "top-down", "aligned", "aligned:4"} {
+		if _, err := StrategyByName(name); err != nil {
+			t.Errorf("StrategyByName(%q) failed: %s", name, err)
+		}
+	}
+	for _, name := range []string{"worst-fit", "aligned:", "aligned:0", "aligned:x"} {
+		if _, err := StrategyByName(name); err == nil {
+			t.Errorf("StrategyByName(%q) should have failed", name)
+		}
+	}
+}
+
+func TestAllocFrom(t *testing.T) {
+	small, err := NewIPNetPool("10.20.0.0/22", CIDR("10.20.0.0/23"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	large, err := NewIPNetPool("172.22.0.0/20")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pools := []*IPNetPool{large, small}
+
+	// the free /23 of the small pool fits best
+	pool, ipnet, err := AllocFrom(pools, 24, BestFit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pool != small || ipnet.String() != "10.20.2.0/24" {
+		t.Fatalf("expected 10.20.2.0/24 from the small pool, got %s", ipnet)
+	}
+
+	pool, ipnet, err = AllocFrom(pools, 23, BestFit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pool != large || ipnet.String() != "172.22.0.0/23" {
+		t.Fatalf("expected 172.22.0.0/23 from the large pool, got %s", ipnet)
+	}
+
+	if _, _, err = AllocFrom(pools, 19, BestFit{}); err == nil {
+		t.Fatal("expected an error for a network larger than every pool")
+	}
+}
+
+func TestAllocFrom_AddressOrder(t *testing.T) {
+	tests := []struct {
+		strategy Strategy
+		want     string
+	}{
+		{FirstFit{}, "10.20.0.0/24"},
+		{TopDown{}, "172.22.15.0/24"},
+	}
+	for _, tt := range tests {
+		// the superblocks are listed in the file in reverse order
+		high, err := NewIPNetPool("172.22.0.0/20")
+		if err != nil {
+			t.Fatal(err)
+		}
+		low, err := NewIPNetPool("10.20.0.0/16")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, ipnet, err := AllocFrom([]*IPNetPool{high, low}, 24, tt.strategy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ipnet.String() != tt.want {
+			t.Errorf("%T picked %s, want %s", tt.strategy, ipnet, tt.want)
+		}
+	}
+}
+
+// outsideStrategy picks a network no pool contains
+type outsideStrategy struct{}
+
+func (outsideStrategy) Pick(blocks []*Block, requestedSize int) *net.IPNet {
+	return CIDR("192.168.0.0/24")
+}
+
+func TestAllocFrom_StrategyOutsidePools(t *testing.T) {
+	pool, err := NewIPNetPool("10.20.0.0/22")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := AllocFrom([]*IPNetPool{pool}, 24, outsideStrategy{}); err == nil {
+		t.Fatal("expected an error for a network outside of all pools")
+	}
+}
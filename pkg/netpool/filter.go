@@ -0,0 +1,50 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package netpool
+
+import (
+	"atfutil/pkg/atf"
+)
+
+// Filter returns a copy of the file with only the allocations match accepts.
+// An accepted allocation nested in one that is not accepted moves up to its
+// closest accepted ancestor, the original file is not modified.
+func (patf *ParsedATF) Filter(match func(alloc *atf.Allocation) bool) (*ParsedATF, error) {
+	file := *patf.File
+	file.Allocations = filterAllocations(patf.File.Allocations, match)
+	return FromAtf(&file)
+}
+
+func filterAllocations(allocations []*atf.Allocation, match func(alloc *atf.Allocation) bool) []*atf.Allocation {
+	filtered := make([]*atf.Allocation, 0)
+	for _, alloc := range allocations {
+		subAllocs := filterAllocations(alloc.SubAlloc, match)
+		if !match(alloc) {
+			filtered = append(filtered, subAllocs...)
+			continue
+		}
+		copied := *alloc
+		copied.SubAlloc = subAllocs
+		filtered = append(filtered, &copied)
+	}
+	return filtered
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package netpool
+
+import (
+	"strings"
+	"testing"
+
+	"atfutil/pkg/atf"
+)
+
+// tree writes the idents of the allocations with suballocations in brackets
+func tree(allocations []*atf.Allocation) string {
+	idents := make([]string, 0, len(allocations))
+	for _, alloc := range allocations {
+		ident := alloc.Ident
+		if len(alloc.SubAlloc) > 0 {
+			ident += "(" + tree(alloc.SubAlloc) + ")"
+		}
+		idents = append(idents, ident)
+	}
+	return strings.Join(idents, " ")
+}
+
+func TestParsedATF_Filter(t *testing.T) {
+	tests := []struct {
+		name   string
+		idents []string
+		want   string
+	}{
+		{"all", []string{"first", "sub", "nested", "leaf", "second"}, "first(sub nested(leaf)) second"},
+		{"top-level", []string{"first", "second"}, "first second"},
+		{"hoist to the top", []string{"leaf", "second"}, "leaf second"},
+		{"hoist to an ancestor", []string{"first", "leaf"}, "first(leaf)"},
+		{"hoist siblings", []string{"sub", "nested"}, "sub nested"},
+		{"none", []string{}, ""},
+	}
+	parsed := parseFixture(t, fixtureText)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filtered, err := parsed.Filter(func(alloc *atf.Allocation) bool {
+				for _, ident := range tt.idents {
+					if alloc.Ident == ident {
+						return true
+					}
+				}
+				return false
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := tree(filtered.File.Allocations); got != tt.want {
+				t.Errorf("Filter() = %q, want %q", got, tt.want)
+			}
+			for _, ident := range tt.idents {
+				alloc := filtered.GetAtfAllocationByIdent(ident)
+				if alloc == nil || filtered.GetAtfAllocationByNet(alloc.Network.String()) != alloc {
+					t.Errorf("%s is not in the filtered pools", ident)
+				}
+			}
+			if got := tree(parsed.File.Allocations); got != "first(sub nested(leaf)) second" {
+				t.Errorf("Filter() modified the original file: %q", got)
+			}
+		})
+	}
+}
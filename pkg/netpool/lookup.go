@@ -0,0 +1,84 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package netpool
+
+import (
+	"net"
+
+	"atfutil/pkg/atf"
+	"atfutil/pkg/netcalc"
+)
+
+// LookupResult describes where a network sits in the file
+type LookupResult struct {
+	// Superblock is the superblock containing the network
+	Superblock *net.IPNet
+	// Chain lists the allocations containing the network, outermost first
+	Chain []*atf.Allocation
+	// Free is the free block containing the network, nil if it is allocated
+	Free *net.IPNet
+	// Split is set when the network spans several blocks nested in the last
+	// allocation of Chain (or the superblock)
+	Split bool
+}
+
+// Lookup finds the allocations containing the given network, nil is returned
+// when it is outside of every superblock
+func (patf *ParsedATF) Lookup(ipnet *net.IPNet) *LookupResult {
+	pool := patf.PoolFor(ipnet)
+	if pool == nil || !contains(pool.Super(), ipnet) {
+		return nil
+	}
+
+	result := &LookupResult{Superblock: pool.Super(), Chain: make([]*atf.Allocation, 0)}
+	// stop early when the network is exactly the superblock or an allocation
+	for pool != nil && pool.Super().String() != ipnet.String() {
+		var found *netcalc.Block
+		for _, block := range pool.FindAllAllocations() {
+			if contains(block.Net, ipnet) {
+				found = block
+				break
+			}
+		}
+		if found == nil {
+			result.Split = true
+			return result
+		}
+
+		netstring := found.Net.String()
+		alloc := patf.GetAtfAllocationByNet(netstring)
+		if !found.Alloc || alloc == nil {
+			result.Free = found.Net
+			return result
+		}
+		result.Chain = append(result.Chain, alloc)
+		pool = patf.GetPoolByNet(netstring)
+	}
+	return result
+}
+
+// contains reports whether inner is entirely inside outer
+func contains(outer *net.IPNet, inner *net.IPNet) bool {
+	outerSize, _ := outer.Mask.Size()
+	innerSize, _ := inner.Mask.Size()
+	return outer.Contains(inner.IP) && outerSize <= innerSize
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package netpool
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestParsedATF_Lookup(t *testing.T) {
+	tests := []struct {
+		cidr string
+		// chain are the idents of the containing allocations, nil when the
+		// network is outside of every superblock
+		chain []string
+		free  string
+		split bool
+	}{
+		{"10.0.0.70/32", []string{"first", "nested", "leaf"}, "", false},
+		{"10.0.0.200/32", []string{"first"}, "10.0.0.128/25", false},
+		{"10.0.3.5/32", []string{}, "10.0.3.0/24", false},
+		{"10.0.0.64/26", []string{"first", "nested"}, "", false},
+		{"10.0.0.0/22", []string{}, "", false},
+		{"10.0.0.0/23", []string{}, "", true},
+		{"10.0.0.0/25", []string{"first"}, "", true},
+		{"10.1.0.0/24", nil, "", false},
+		{"10.0.0.0/21", nil, "", false},
+	}
+	parsed := parseFixture(t, fixtureText)
+	for _, tt := range tests {
+		t.Run(tt.cidr, func(t *testing.T) {
+			_, ipnet, _ := net.ParseCIDR(tt.cidr)
+			result := parsed.Lookup(ipnet)
+			if tt.chain == nil {
+				if result != nil {
+					t.Errorf("Lookup() = %+v, want nil", result)
+				}
+				return
+			}
+			if result == nil {
+				t.Fatal("Lookup() = nil")
+			}
+			if result.Superblock.String() != "10.0.0.0/22" {
+				t.Errorf("Superblock = %s", result.Superblock)
+			}
+			chain := make([]string, 0, len(result.Chain))
+			for _, alloc := range result.Chain {
+				chain = append(chain, alloc.Ident)
+			}
+			if !reflect.DeepEqual(chain, tt.chain) {
+				t.Errorf("Chain = %v, want %v", chain, tt.chain)
+			}
+			free := ""
+			if result.Free != nil {
+				free = result.Free.String()
+			}
+			if free != tt.free {
+				t.Errorf("Free = %q, want %q", free, tt.free)
+			}
+			if result.Split != tt.split {
+				t.Errorf("Split = %v, want %v", result.Split, tt.split)
+			}
+		})
+	}
+}
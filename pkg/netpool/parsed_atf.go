@@ -24,16 +24,22 @@ package netpool
 import (
 	"atfutil/pkg/atf"
 	"atfutil/pkg/netcalc"
-	"errors"
 	"log"
 	"net"
+
+	"github.com/pkg/errors"
 )
 
 type ParsedATF struct {
-	File               *atf.File
-	Pool               *netcalc.IPNetPool
+	File *atf.File
+	// Pools has one pool per superblock, in the order of File.AllSuperblocks
+	Pools              []*netcalc.IPNetPool
+	Strategy           netcalc.Strategy
+	Provider           netcalc.Provider
 	subAllocationsPool map[string]*netcalc.IPNetPool
 	subAllocationsFile map[string]*atf.Allocation
+	parentNet          map[string]string
+	depth              map[string]int
 }
 
 func (patf *ParsedATF) GetPoolByNet(netstring string) *netcalc.IPNetPool {
@@ -46,45 +52,224 @@ func (patf *ParsedATF) GetAtfAllocationByNet(netstring string) *atf.Allocation {
 	return alloc
 }
 
-func FromAtf(atfFile *atf.File) (*ParsedATF, error) {
-	var subAllocPool map[string]*netcalc.IPNetPool = make(map[string]*netcalc.IPNetPool, 128)
-	var subAllocFile map[string]*atf.Allocation = make(map[string]*atf.Allocation, 128)
+// GetParentByNet returns the allocation the given allocation is nested in,
+// nil for top-level allocations
+func (patf *ParsedATF) GetParentByNet(netstring string) *atf.Allocation {
+	parent, ok := patf.parentNet[netstring]
+	if !ok {
+		return nil
+	}
+	return patf.subAllocationsFile[parent]
+}
 
-	parsed, err := fromAllocations(atfFile.Superblock.String(), atfFile.Allocations, 0, subAllocPool, subAllocFile)
+// GetDepthByNet returns how deeply the given allocation is nested, 0 for
+// top-level allocations
+func (patf *ParsedATF) GetDepthByNet(netstring string) int {
+	return patf.depth[netstring]
+}
+
+// GetOrCreatePoolByNet returns the pool of suballocations of the given
+// allocation, an empty pool is created if it has none yet
+func (patf *ParsedATF) GetOrCreatePoolByNet(netstring string) (*netcalc.IPNetPool, error) {
+	if pool := patf.GetPoolByNet(netstring); pool != nil {
+		return pool, nil
+	}
+	if patf.GetAtfAllocationByNet(netstring) == nil {
+		return nil, errors.Errorf("no allocation %s", netstring)
+	}
+	pool, err := netcalc.NewIPNetPool(netstring)
 	if err != nil {
 		return nil, err
 	}
-	parsed.File = atfFile
-	parsed.subAllocationsFile = subAllocFile
-	parsed.subAllocationsPool = subAllocPool
+	patf.subAllocationsPool[netstring] = pool
+	return pool, nil
+}
+
+// Release removes the given allocation from both the file and the pools.
+// Allocations with suballocations are only released if recursive is set.
+func (patf *ParsedATF) Release(netstring string, recursive bool) error {
+	alloc := patf.GetAtfAllocationByNet(netstring)
+	if alloc == nil {
+		return errors.Errorf("no allocation %s", netstring)
+	}
+	if len(alloc.SubAlloc) > 0 && !recursive {
+		return errors.Errorf("allocation %s has %d suballocations, release them first or release recursively", netstring, len(alloc.SubAlloc))
+	}
+
+	pool := patf.PoolFor(alloc.Network.IPNet)
+	siblings := &patf.File.Allocations
+	parent := patf.GetParentByNet(netstring)
+	if parent != nil {
+		pool = patf.GetPoolByNet(parent.Network.String())
+		siblings = &parent.SubAlloc
+	}
+	if err := pool.Release(alloc.Network.IPNet); err != nil {
+		return err
+	}
+	for i, sibling := range *siblings {
+		if sibling == alloc {
+			*siblings = append((*siblings)[:i], (*siblings)[i+1:]...)
+			break
+		}
+	}
+	// match what FromAtf would build for the modified file
+	if parent != nil && len(parent.SubAlloc) == 0 {
+		delete(patf.subAllocationsPool, parent.Network.String())
+	}
+	patf.forget(alloc)
+	return nil
+}
+
+// forget drops an allocation and everything nested in it from the lookup maps
+func (patf *ParsedATF) forget(alloc *atf.Allocation) {
+	netstring := alloc.Network.String()
+	delete(patf.subAllocationsFile, netstring)
+	delete(patf.subAllocationsPool, netstring)
+	delete(patf.parentNet, netstring)
+	delete(patf.depth, netstring)
+	for _, subAlloc := range alloc.SubAlloc {
+		patf.forget(subAlloc)
+	}
+}
+
+// GetAtfAllocationByIdent returns the first allocation (in file order,
+// parents before their suballocations) with the given ident
+func (patf *ParsedATF) GetAtfAllocationByIdent(ident string) *atf.Allocation {
+	return findByIdent(patf.File.Allocations, ident)
+}
+
+// FindAllocation looks up an allocation by either its CIDR or its ident
+func (patf *ParsedATF) FindAllocation(cidrOrIdent string) *atf.Allocation {
+	if _, ipnet, err := net.ParseCIDR(cidrOrIdent); err == nil {
+		return patf.GetAtfAllocationByNet(ipnet.String())
+	}
+	return patf.GetAtfAllocationByIdent(cidrOrIdent)
+}
+
+func findByIdent(allocations []*atf.Allocation, ident string) *atf.Allocation {
+	for _, alloc := range allocations {
+		if alloc.Ident == ident {
+			return alloc
+		}
+		if found := findByIdent(alloc.SubAlloc, ident); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func FromAtf(atfFile *atf.File) (*ParsedATF, error) {
+	if err := atfFile.Validate(); err != nil {
+		return nil, err
+	}
+	strategy, err := netcalc.StrategyByName(atfFile.Strategy)
+	if err != nil {
+		return nil, &atf.KeyError{Key: "strategy", Err: err}
+	}
+	provider, err := netcalc.ProviderByName(atfFile.Provider)
+	if err != nil {
+		return nil, &atf.KeyError{Key: "provider", Err: err}
+	}
+
+	parsed := &ParsedATF{
+		File:               atfFile,
+		Strategy:           strategy,
+		Provider:           provider,
+		subAllocationsPool: make(map[string]*netcalc.IPNetPool, 128),
+		subAllocationsFile: make(map[string]*atf.Allocation, 128),
+		parentNet:          make(map[string]string, 128),
+		depth:              make(map[string]int, 128),
+	}
+
+	// top-level allocations go into the pool of the superblock containing them
+	supers := atfFile.AllSuperblocks()
+	grouped := make([][]*atf.Allocation, len(supers))
+	for _, alloc := range atfFile.Allocations {
+		if alloc.Network == nil {
+			return nil, &atf.AllocationError{Allocation: alloc, Err: errors.New("allocation has no cidr")}
+		}
+		i := superblockIndex(supers, alloc.Network.IPNet)
+		if i < 0 {
+			return nil, &atf.AllocationError{Allocation: alloc, Err: errors.Errorf("allocation %s is not inside any superblock", alloc.Network.String())}
+		}
+		grouped[i] = append(grouped[i], alloc)
+	}
+
+	parsed.Pools = make([]*netcalc.IPNetPool, 0, len(supers))
+	for i, super := range supers {
+		pool, err := parsed.fromAllocations(super.String(), grouped[i], 0)
+		if err != nil {
+			return nil, err
+		}
+		parsed.Pools = append(parsed.Pools, pool)
+	}
 
 	return parsed, nil
 }
 
-func fromAllocations(super string, allocations []*atf.Allocation, depth int, subAllocPool map[string]*netcalc.IPNetPool, subAllocFile map[string]*atf.Allocation) (*ParsedATF, error) {
-	if depth >= 2 {
-		return nil, errors.New("nested suballocations are not supported")
+// superblockIndex returns the index of the superblock the network starts in,
+// -1 if there is none
+func superblockIndex(supers []*atf.IPNet, ipnet *net.IPNet) int {
+	for i, super := range supers {
+		if netcalc.SameFamily(super.IPNet, ipnet) && super.Contains(ipnet.IP) {
+			return i
+		}
+	}
+	return -1
+}
+
+// PoolFor returns the pool of the superblock containing the given network,
+// nil if it is outside of every superblock
+func (patf *ParsedATF) PoolFor(ipnet *net.IPNet) *netcalc.IPNetPool {
+	for _, pool := range patf.Pools {
+		if netcalc.SameFamily(pool.Super(), ipnet) && pool.Super().Contains(ipnet.IP) {
+			return pool
+		}
 	}
+	return nil
+}
+
+func (patf *ParsedATF) fromAllocations(super string, allocations []*atf.Allocation, depth int) (*netcalc.IPNetPool, error) {
 	ipNet := make([]*net.IPNet, 0, len(allocations))
 	for _, alloc := range allocations {
+		if alloc.Network == nil {
+			return nil, &atf.AllocationError{Allocation: alloc, Err: errors.New("allocation has no cidr")}
+		}
 		networkName := alloc.Network.String()
 		ipNet = append(ipNet, alloc.Network.IPNet)
 		if len(alloc.SubAlloc) >= 1 {
-			subPool, err := fromAllocations(networkName, alloc.SubAlloc, depth+1, subAllocPool, subAllocFile)
+			subPool, err := patf.fromAllocations(networkName, alloc.SubAlloc, depth+1)
 			if err != nil {
 				return nil, err
 			}
 			log.Printf("ASSIGN SUBNET %s\t%v", networkName, alloc)
-			subAllocPool[networkName] = subPool.Pool
+			patf.subAllocationsPool[networkName] = subPool
 		}
 		log.Printf("ASSIGN NET    %s\t%v", networkName, alloc)
-		subAllocFile[networkName] = alloc
+		patf.subAllocationsFile[networkName] = alloc
+		patf.depth[networkName] = depth
+		if depth > 0 {
+			patf.parentNet[networkName] = super
+		}
 	}
 	pool, err := netcalc.NewIPNetPool(super, ipNet...)
 	if err != nil {
-		return nil, err
+		return nil, blameAllocation(super, allocations, err)
+	}
+	return pool, nil
+}
+
+// blameAllocation finds the first allocation that makes building the pool
+// fail, so the error can point at it
+func blameAllocation(super string, allocations []*atf.Allocation, err error) error {
+	for i := range allocations {
+		ipNet := make([]*net.IPNet, 0, i+1)
+		for _, alloc := range allocations[:i+1] {
+			ipNet = append(ipNet, alloc.Network.IPNet)
+		}
+		if _, allocErr := netcalc.NewIPNetPool(super, ipNet...); allocErr != nil {
+			return &atf.AllocationError{Allocation: allocations[i], Err: allocErr}
+		}
 	}
-	return &ParsedATF{
-		Pool: pool,
-	}, nil
+	return err
 }
@@ -0,0 +1,186 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package netpool
+
+import (
+	"testing"
+
+	"atfutil/pkg/atf"
+	"atfutil/pkg/netcalc"
+)
+
+// fixtureText has nested allocations, a reserved one and free space at every
+// level
+const fixtureText = `superBlock: 10.0.0.0/22
+allocations:
+- cidr: 10.0.0.0/24
+  ident: first
+  subAlloc:
+  - cidr: 10.0.0.0/26
+    ident: sub
+    reserved: true
+  - cidr: 10.0.0.64/26
+    ident: nested
+    subAlloc:
+    - cidr: 10.0.0.64/28
+      ident: leaf
+- cidr: 10.0.2.0/24
+  ident: second
+  description: web servers
+`
+
+func parseFixture(t *testing.T, text string) *ParsedATF {
+	t.Helper()
+	doc, err := atf.ParseDocument([]byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := FromAtf(doc.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+// allocated reports whether the pool has the network as an allocated block
+func allocated(pool *netcalc.IPNetPool, cidr string) bool {
+	for _, block := range pool.FindAllAllocations() {
+		if block.Net.String() == cidr {
+			return block.Alloc
+		}
+	}
+	return false
+}
+
+func countAllocations(allocations []*atf.Allocation) int {
+	count := len(allocations)
+	for _, alloc := range allocations {
+		count += countAllocations(alloc.SubAlloc)
+	}
+	return count
+}
+
+func TestParsedATF_Release(t *testing.T) {
+	tests := []struct {
+		name      string
+		cidr      string
+		recursive bool
+		wantErr   bool
+		// parent is the pool the network was in, empty for the superblock
+		parent string
+		// gone are the networks that must be forgotten afterwards
+		gone []string
+	}{
+		{"top-level", "10.0.2.0/24", false, false, "", []string{"10.0.2.0/24"}},
+		{"suballocation", "10.0.0.0/26", false, false, "10.0.0.0/24", []string{"10.0.0.0/26"}},
+		{"last suballocation", "10.0.0.64/28", false, false, "10.0.0.64/26", []string{"10.0.0.64/28"}},
+		{"has suballocations", "10.0.0.64/26", false, true, "10.0.0.0/24", nil},
+		{"recursive", "10.0.0.64/26", true, false, "10.0.0.0/24", []string{"10.0.0.64/26", "10.0.0.64/28"}},
+		{"recursive top-level", "10.0.0.0/24", true, false, "", []string{"10.0.0.0/24", "10.0.0.0/26", "10.0.0.64/26", "10.0.0.64/28"}},
+		{"unknown", "10.0.3.0/24", false, true, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed := parseFixture(t, fixtureText)
+			parent := parsed.GetAtfAllocationByNet(tt.parent)
+			siblings := len(parsed.File.Allocations)
+			if parent != nil {
+				siblings = len(parent.SubAlloc)
+			}
+
+			err := parsed.Release(tt.cidr, tt.recursive)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Release() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if countAllocations(parsed.File.Allocations) != 5 {
+					t.Errorf("failed release modified the file")
+				}
+				return
+			}
+
+			for _, cidr := range tt.gone {
+				if parsed.GetAtfAllocationByNet(cidr) != nil {
+					t.Errorf("%s is still known", cidr)
+				}
+				if parsed.GetPoolByNet(cidr) != nil {
+					t.Errorf("%s still has a pool", cidr)
+				}
+			}
+			pool := parsed.Pools[0]
+			remaining := len(parsed.File.Allocations)
+			if parent != nil {
+				pool = parsed.GetPoolByNet(tt.parent)
+				remaining = len(parent.SubAlloc)
+			}
+			if remaining != siblings-1 {
+				t.Errorf("%d allocations remain next to %s, want %d", remaining, tt.cidr, siblings-1)
+			}
+			// an empty pool is dropped, like FromAtf does for the modified file
+			if pool != nil && allocated(pool, tt.cidr) {
+				t.Errorf("%s is still allocated in its pool", tt.cidr)
+			}
+			if pool == nil && remaining > 0 {
+				t.Errorf("pool of %s was dropped although it has allocations", tt.parent)
+			}
+		})
+	}
+}
+
+func TestParsedATF_GetOrCreatePoolByNet(t *testing.T) {
+	parsed := parseFixture(t, fixtureText)
+
+	// first already has suballocations, its pool is returned as is
+	existing, err := parsed.GetOrCreatePoolByNet("10.0.0.0/24")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if existing != parsed.GetPoolByNet("10.0.0.0/24") || !allocated(existing, "10.0.0.0/26") {
+		t.Error("the existing pool of first is not returned")
+	}
+
+	// second has none, an empty pool is created and kept
+	if parsed.GetPoolByNet("10.0.2.0/24") != nil {
+		t.Fatal("second already has a pool")
+	}
+	created, err := parsed.GetOrCreatePoolByNet("10.0.2.0/24")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Super().String() != "10.0.2.0/24" || len(created.FreeBlocks()) != 2 {
+		t.Errorf("unexpected pool %s with free blocks %v", created.Super(), created.FreeBlocks())
+	}
+	ipnet, err := created.Alloc(26)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ipnet.String() != "10.0.2.0/26" {
+		t.Errorf("allocated %s in the new pool, want 10.0.2.0/26", ipnet)
+	}
+	if parsed.GetPoolByNet("10.0.2.0/24") != created {
+		t.Error("the created pool is not kept")
+	}
+
+	if _, err := parsed.GetOrCreatePoolByNet("10.0.3.0/24"); err == nil {
+		t.Error("expected an error for a network that is not allocated")
+	}
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package netpool
+
+import (
+	"math/big"
+	"net"
+
+	"atfutil/pkg/netcalc"
+)
+
+// Stats describes the utilisation of the superblock or of one allocation
+// with suballocations
+type Stats struct {
+	CIDR  string `json:"cidr"`
+	Ident string `json:"ident,omitempty"`
+	// Depth is 0 for the superblock, 1 for top-level allocations and so on
+	Depth     int      `json:"depth"`
+	Total     *big.Int `json:"total"`
+	Allocated *big.Int `json:"allocated"`
+	Reserved  *big.Int `json:"reserved"`
+	Free      *big.Int `json:"free"`
+	// UsedPercent is the share of allocated and reserved addresses
+	UsedPercent float64 `json:"usedPercent"`
+	// LargestFree is the largest free block, empty when everything is used
+	LargestFree string `json:"largestFree,omitempty"`
+	// Fragmentation is 0 when all free space is contiguous and approaches 1
+	// the more it is split up by allocations
+	Fragmentation float64 `json:"fragmentation"`
+	// Histogram counts the allocations directly in the pool by prefix length
+	Histogram map[int]int `json:"histogram"`
+}
+
+// Stats returns the statistics of every superblock, each followed by those of
+// the allocations with suballocations in it
+func (patf *ParsedATF) Stats() []*Stats {
+	stats := make([]*Stats, 0)
+	for _, pool := range patf.Pools {
+		stats = append(stats, patf.poolStats(pool, 0))
+		stats = patf.collectStats(pool, 1, stats)
+	}
+	return stats
+}
+
+func (patf *ParsedATF) collectStats(pool *netcalc.IPNetPool, depth int, stats []*Stats) []*Stats {
+	for _, block := range pool.FindAllAllocations() {
+		if subPool := patf.GetPoolByNet(block.Net.String()); block.Alloc && subPool != nil {
+			stats = append(stats, patf.poolStats(subPool, depth))
+			stats = patf.collectStats(subPool, depth+1, stats)
+		}
+	}
+	return stats
+}
+
+// poolStats calculates the statistics of one pool, depth is 0 for the
+// superblock
+func (patf *ParsedATF) poolStats(pool *netcalc.IPNetPool, depth int) *Stats {
+	usage := patf.Usage(pool)
+	stats := &Stats{
+		CIDR:        pool.Super().String(),
+		Depth:       depth,
+		Total:       usage.Total,
+		Allocated:   usage.Allocated,
+		Reserved:    usage.Reserved,
+		Free:        usage.Free,
+		UsedPercent: usage.UsedPercent(),
+		Histogram:   make(map[int]int),
+	}
+	if alloc := patf.GetAtfAllocationByNet(stats.CIDR); alloc != nil && depth > 0 {
+		stats.Ident = alloc.Ident
+	}
+
+	// free blocks are aligned, so contiguous free space is split into several
+	// of them which are merged into ranges again
+	var largest *net.IPNet
+	largestLen := 0
+	largestRange, freeRange := new(big.Int), new(big.Int)
+	for _, block := range pool.FindAllAllocations() {
+		prefixLen, _ := block.Net.Mask.Size()
+		if block.Alloc {
+			stats.Histogram[prefixLen]++
+			freeRange = new(big.Int)
+			continue
+		}
+		freeRange.Add(freeRange, netcalc.AddressCount(block.Net))
+		if freeRange.Cmp(largestRange) > 0 {
+			largestRange.Set(freeRange)
+		}
+		if largest == nil || prefixLen < largestLen {
+			largest = block.Net
+			largestLen = prefixLen
+		}
+	}
+	if largestRange.Cmp(usage.Total) == 0 {
+		// nothing allocated, the free blocks never contain the whole pool
+		largest = pool.Super()
+	}
+	if largest != nil {
+		stats.LargestFree = largest.String()
+		share, _ := new(big.Float).Quo(
+			new(big.Float).SetInt(largestRange),
+			new(big.Float).SetInt(usage.Free),
+		).Float64()
+		stats.Fragmentation = 1 - share
+	}
+	return stats
+}
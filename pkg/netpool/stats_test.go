@@ -0,0 +1,99 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package netpool
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestParsedATF_Stats(t *testing.T) {
+	tests := []struct {
+		cidr          string
+		ident         string
+		depth         int
+		largestFree   string
+		fragmentation float64
+		histogram     map[int]int
+	}{
+		{"10.0.0.0/22", "", 0, "10.0.1.0/24", 0.5, map[int]int{24: 2}},
+		{"10.0.0.0/24", "first", 1, "10.0.0.128/25", 0, map[int]int{26: 2}},
+		{"10.0.0.64/26", "nested", 2, "10.0.0.96/27", 0, map[int]int{28: 1}},
+	}
+	stats := parseFixture(t, fixtureText).Stats()
+	if len(stats) != len(tests) {
+		t.Fatalf("Stats() returned %d entries, want %d", len(stats), len(tests))
+	}
+	for i, tt := range tests {
+		t.Run(tt.cidr, func(t *testing.T) {
+			got := stats[i]
+			if got.CIDR != tt.cidr || got.Ident != tt.ident || got.Depth != tt.depth {
+				t.Errorf("got %s %q at depth %d, want %s %q at depth %d", got.CIDR, got.Ident, got.Depth, tt.cidr, tt.ident, tt.depth)
+			}
+			if got.LargestFree != tt.largestFree {
+				t.Errorf("LargestFree = %q, want %q", got.LargestFree, tt.largestFree)
+			}
+			if math.Abs(got.Fragmentation-tt.fragmentation) > 1e-9 {
+				t.Errorf("Fragmentation = %v, want %v", got.Fragmentation, tt.fragmentation)
+			}
+			if !reflect.DeepEqual(got.Histogram, tt.histogram) {
+				t.Errorf("Histogram = %v, want %v", got.Histogram, tt.histogram)
+			}
+		})
+	}
+}
+
+func TestParsedATF_StatsFull(t *testing.T) {
+	stats := parseFixture(t, "superBlock: 10.1.0.0/24\nallocations:\n- cidr: 10.1.0.0/25\n  ident: low\n- cidr: 10.1.0.128/25\n  ident: high\n").Stats()
+	if len(stats) != 1 {
+		t.Fatalf("Stats() returned %d entries, want 1", len(stats))
+	}
+	got := stats[0]
+	if got.LargestFree != "" || got.Fragmentation != 0 || got.UsedPercent != 100 {
+		t.Errorf("full superblock has LargestFree %q, Fragmentation %v, UsedPercent %v", got.LargestFree, got.Fragmentation, got.UsedPercent)
+	}
+	if !reflect.DeepEqual(got.Histogram, map[int]int{25: 2}) {
+		t.Errorf("Histogram = %v", got.Histogram)
+	}
+}
+
+func TestParsedATF_StatsUnfragmented(t *testing.T) {
+	tests := []struct {
+		text        string
+		largestFree string
+	}{
+		{"superBlock: 10.1.0.0/16\nallocations: []\n", "10.1.0.0/16"},
+		{"superBlock: 10.1.0.0/16\nallocations:\n- cidr: 10.1.0.0/24\n  ident: bottom\n", "10.1.128.0/17"},
+		{"superBlock: 10.1.0.0/16\nallocations:\n- cidr: 10.1.255.0/24\n  ident: top\n", "10.1.0.0/17"},
+	}
+	for _, tt := range tests {
+		stats := parseFixture(t, tt.text).Stats()
+		if len(stats) != 1 {
+			t.Fatalf("Stats() returned %d entries, want 1", len(stats))
+		}
+		got := stats[0]
+		if got.LargestFree != tt.largestFree || got.Fragmentation != 0 {
+			t.Errorf("got LargestFree %q, Fragmentation %v for %q, want %q and 0", got.LargestFree, got.Fragmentation, tt.text, tt.largestFree)
+		}
+	}
+}
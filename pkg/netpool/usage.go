@@ -0,0 +1,71 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package netpool
+
+import (
+	"math/big"
+
+	"atfutil/pkg/netcalc"
+)
+
+// Usage sums up the address counts of the blocks directly in one pool,
+// addresses in suballocations count towards their parent
+type Usage struct {
+	Total     *big.Int
+	Allocated *big.Int
+	Reserved  *big.Int
+	Free      *big.Int
+}
+
+// Usage calculates the address usage of the given pool
+func (patf *ParsedATF) Usage(pool *netcalc.IPNetPool) *Usage {
+	usage := &Usage{
+		Total:     netcalc.AddressCount(pool.Super()),
+		Allocated: new(big.Int),
+		Reserved:  new(big.Int),
+		Free:      new(big.Int),
+	}
+	for _, block := range pool.FindAllAllocations() {
+		count := netcalc.AddressCount(block.Net)
+		alloc := patf.GetAtfAllocationByNet(block.Net.String())
+		switch {
+		case !block.Alloc || alloc == nil:
+			usage.Free.Add(usage.Free, count)
+		case alloc.IsReserved:
+			usage.Reserved.Add(usage.Reserved, count)
+		default:
+			usage.Allocated.Add(usage.Allocated, count)
+		}
+	}
+	return usage
+}
+
+// Percent returns the share of count in the total address count, in percent
+func (u *Usage) Percent(count *big.Int) float64 {
+	share, _ := new(big.Float).Quo(new(big.Float).SetInt(count), new(big.Float).SetInt(u.Total)).Float64()
+	return share * 100
+}
+
+// UsedPercent returns the share of allocated and reserved addresses, in percent
+func (u *Usage) UsedPercent() float64 {
+	return u.Percent(new(big.Int).Add(u.Allocated, u.Reserved))
+}
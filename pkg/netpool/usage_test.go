@@ -0,0 +1,66 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package netpool
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestParsedATF_Usage(t *testing.T) {
+	tests := []struct {
+		pool                             string
+		total, allocated, reserved, free int64
+		usedPercent                      float64
+	}{
+		{"10.0.0.0/22", 1024, 512, 0, 512, 50},
+		{"10.0.0.0/24", 256, 64, 64, 128, 50},
+		{"10.0.0.64/26", 64, 16, 0, 48, 25},
+	}
+	parsed := parseFixture(t, fixtureText)
+	for _, tt := range tests {
+		t.Run(tt.pool, func(t *testing.T) {
+			pool := parsed.GetPoolByNet(tt.pool)
+			if pool == nil {
+				pool = parsed.Pools[0]
+			}
+			usage := parsed.Usage(pool)
+			for _, count := range []struct {
+				name string
+				got  *big.Int
+				want int64
+			}{
+				{"Total", usage.Total, tt.total},
+				{"Allocated", usage.Allocated, tt.allocated},
+				{"Reserved", usage.Reserved, tt.reserved},
+				{"Free", usage.Free, tt.free},
+			} {
+				if count.got.Cmp(big.NewInt(count.want)) != 0 {
+					t.Errorf("%s = %s, want %d", count.name, count.got, count.want)
+				}
+			}
+			if usage.UsedPercent() != tt.usedPercent {
+				t.Errorf("UsedPercent() = %v, want %v", usage.UsedPercent(), tt.usedPercent)
+			}
+		})
+	}
+}
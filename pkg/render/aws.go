@@ -0,0 +1,35 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package render
+
+import (
+	"io"
+)
+
+// AWSColumns replace the Azure references with links to the CloudFormation
+// stack, the git repository and the documentation
+var AWSColumns = []string{"alloc", "ident", "block", "suballocation", "hosts", "cloudformation", "git", "documentation", "description"}
+
+// NewDefaultAWSMarkdownRenderer creates a renderer for superblocks used in AWS
+func NewDefaultAWSMarkdownRenderer(target io.Writer) *ColumnMarkdownRenderer {
+	return newColumnSetMarkdownRenderer(target, AWSColumns)
+}
@@ -22,18 +22,9 @@
 package render
 
 import (
-	"atfutil/pkg/atf"
-	"atfutil/pkg/netpool"
-	"fmt"
 	"io"
-	"log"
 )
 
-type DefaultAzureMarkdownRenderer struct {
-	target io.Writer
-	fmtStr string
-}
-
 const (
 	/* MarkerAlloc    = "ALLOC"
 	MarkerFree     = "FREE"
@@ -43,58 +34,17 @@ const (
 	AzureMarkerReserved = "⚠️"
 )
 
-func NewDefaultAzureMarkdownRenderer(target io.Writer) *DefaultAzureMarkdownRenderer {
-	return &DefaultAzureMarkdownRenderer{
-		fmtStr: "|%s|%s|%s|%s|%s|%s|%s|%s|\n",
-		target: target,
-	}
-}
+// AzureColumns are the default columns, with the subscription, resource group
+// and virtual network a subnet is used in
+var AzureColumns = []string{"alloc", "ident", "block", "suballocation", "hosts", "subscription", "resource-group", "vnet", "description"}
 
-func (dmr *DefaultAzureMarkdownRenderer) PrintHeader() {
-	fmt.Fprintf(dmr.target, dmr.fmtStr, "Alloc", "Ident", "Block", "SubAllocation", "Subscription", "Resource Group", "VNET", "Description")
-	fmt.Fprintf(dmr.target, dmr.fmtStr, "-", "-", "-", "-", "-", "-", "-", "-")
+// DefaultAzureMarkdownRenderer prints the AzureColumns
+type DefaultAzureMarkdownRenderer struct {
+	*ColumnMarkdownRenderer
 }
 
-func (dmr *DefaultAzureMarkdownRenderer) PrintAllocation(parsed *netpool.ParsedATF, netstring string, isSub bool, includeFree bool) {
-	//isFree := true
-	alloc := parsed.GetAtfAllocationByNet(netstring)
-	status := AzureMarkerAlloc
-
-	if alloc == nil {
-		log.Printf("ALLOC %s is empty", netstring)
-		alloc = &atf.Allocation{}
-		status = AzureMarkerFree
-	}
-
-	if alloc.IsReserved {
-		status = AzureMarkerReserved
-	}
-
-	var textBlock = netstring
-	var textSubAlloc = ""
-
-	if isSub {
-		textSubAlloc = textBlock
-		textBlock = ""
-	}
-	fmt.Fprintf(
-		dmr.target,
-		dmr.fmtStr,
-		status,
-		alloc.Ident,
-		textBlock,
-		textSubAlloc,
-		alloc.Reference.Azure.Subscription,
-		alloc.Reference.Azure.ResourceGroup,
-		alloc.Reference.Azure.VirtualNetwork,
-		alloc.Description,
-	)
-
-	// we get the (sub) pool here instead of iterating over the actual SubAllocations
-	// from the ATF so we can let FindAllAllocations add free space
-	if subBlock := parsed.GetPoolByNet(netstring); subBlock != nil {
-		for _, block := range subBlock.FindAllAllocations() {
-			dmr.PrintAllocation(parsed, block.Net.String(), true, includeFree)
-		}
-	}
+// NewDefaultAzureMarkdownRenderer creates a renderer for superblocks used in
+// Azure
+func NewDefaultAzureMarkdownRenderer(target io.Writer) *DefaultAzureMarkdownRenderer {
+	return &DefaultAzureMarkdownRenderer{newColumnSetMarkdownRenderer(target, AzureColumns)}
 }
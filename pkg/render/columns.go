@@ -0,0 +1,220 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package render
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"sort"
+	"strings"
+
+	"atfutil/pkg/atf"
+	"atfutil/pkg/netcalc"
+	"atfutil/pkg/netpool"
+
+	"github.com/pkg/errors"
+)
+
+// MarkdownColumn is a column the ColumnMarkdownRenderer can print
+type MarkdownColumn struct {
+	Header string
+	Value  func(row *MarkdownRow) string
+}
+
+// MarkdownRow is the block a row is printed for, Allocation is empty for
+// free blocks
+type MarkdownRow struct {
+	Net        string
+	Depth      int
+	Marker     string
+	Allocation *atf.Allocation
+	// Hosts is the number of usable hosts, empty for free ranges
+	Hosts string
+}
+
+// MarkdownColumns are all columns available for --columns, by name
+var MarkdownColumns = map[string]*MarkdownColumn{
+	"alloc": {"Alloc", func(row *MarkdownRow) string { return row.Marker }},
+	"ident": {"Ident", func(row *MarkdownRow) string { return row.Allocation.Ident }},
+	"block": {"Block", func(row *MarkdownRow) string {
+		if row.Depth > 0 {
+			return ""
+		}
+		return row.Net
+	}},
+	"suballocation": {"SubAllocation", func(row *MarkdownRow) string {
+		if row.Depth == 0 {
+			return ""
+		}
+		return strings.Repeat("↳", row.Depth-1) + row.Net
+	}},
+	"cidr":           {"CIDR", func(row *MarkdownRow) string { return strings.Repeat("↳", row.Depth) + row.Net }},
+	"hosts":          {"Hosts", func(row *MarkdownRow) string { return row.Hosts }},
+	"description":    {"Description", func(row *MarkdownRow) string { return row.Allocation.Description }},
+	"subscription":   {"Subscription", func(row *MarkdownRow) string { return row.Allocation.Reference.Azure.Subscription }},
+	"resource-group": {"Resource Group", func(row *MarkdownRow) string { return row.Allocation.Reference.Azure.ResourceGroup }},
+	"vnet":           {"VNET", func(row *MarkdownRow) string { return row.Allocation.Reference.Azure.VirtualNetwork }},
+	"cloudformation": {"CloudFormation", func(row *MarkdownRow) string {
+		return markdownLink("stack", row.Allocation.Reference.AWS.CloudFormationURL)
+	}},
+	"git": {"Git", func(row *MarkdownRow) string {
+		return markdownLink("repo", stringValue(row.Allocation.Reference.Git))
+	}},
+	"documentation": {"Documentation", func(row *MarkdownRow) string {
+		return markdownLink("docs", stringValue(row.Allocation.Reference.DocumentationURI))
+	}},
+}
+
+// MarkdownColumnSets are the predefined column sets for --columns
+var MarkdownColumnSets = map[string][]string{
+	"azure":   AzureColumns,
+	"aws":     AWSColumns,
+	"generic": {"alloc", "ident", "block", "suballocation", "hosts", "description"},
+}
+
+// ColumnMarkdownRenderer prints a table with a configurable set of columns
+type ColumnMarkdownRenderer struct {
+	target  io.Writer
+	columns []*MarkdownColumn
+}
+
+// NewColumnMarkdownRenderer creates a renderer printing the given columns,
+// see MarkdownColumns for the available names
+func NewColumnMarkdownRenderer(target io.Writer, names []string) (*ColumnMarkdownRenderer, error) {
+	if len(names) == 0 {
+		return nil, errors.New("no columns to render")
+	}
+	columns := make([]*MarkdownColumn, 0, len(names))
+	for _, name := range names {
+		column, ok := MarkdownColumns[strings.TrimSpace(name)]
+		if !ok {
+			return nil, errors.Errorf("unknown column '%s', available columns: %s", name, strings.Join(MarkdownColumnNames(), ", "))
+		}
+		columns = append(columns, column)
+	}
+	return &ColumnMarkdownRenderer{
+		target:  target,
+		columns: columns,
+	}, nil
+}
+
+// newColumnSetMarkdownRenderer creates a renderer for one of the
+// MarkdownColumnSets, their names are all in MarkdownColumns
+func newColumnSetMarkdownRenderer(target io.Writer, set []string) *ColumnMarkdownRenderer {
+	columns := make([]*MarkdownColumn, 0, len(set))
+	for _, name := range set {
+		columns = append(columns, MarkdownColumns[name])
+	}
+	return &ColumnMarkdownRenderer{
+		target:  target,
+		columns: columns,
+	}
+}
+
+// NewMarkdownRenderer creates the renderer for --columns, either the name of
+// one of the MarkdownColumnSets or a list of column names
+func NewMarkdownRenderer(target io.Writer, columns []string) (MarkdownRenderer, error) {
+	if len(columns) == 1 {
+		if set, ok := MarkdownColumnSets[columns[0]]; ok {
+			return newColumnSetMarkdownRenderer(target, set), nil
+		}
+	}
+	return NewColumnMarkdownRenderer(target, columns)
+}
+
+// MarkdownColumnNames returns the names of all columns, sorted
+func MarkdownColumnNames() []string {
+	names := make([]string, 0, len(MarkdownColumns))
+	for name := range MarkdownColumns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (cmr *ColumnMarkdownRenderer) PrintHeader() {
+	headers := make([]string, 0, len(cmr.columns))
+	separators := make([]string, 0, len(cmr.columns))
+	for _, column := range cmr.columns {
+		headers = append(headers, column.Header)
+		separators = append(separators, "-")
+	}
+	cmr.printRow(headers)
+	cmr.printRow(separators)
+}
+
+func (cmr *ColumnMarkdownRenderer) PrintAllocation(parsed *netpool.ParsedATF, netstring string, depth int, free FreeMode) {
+	row := &MarkdownRow{
+		Net:        netstring,
+		Depth:      depth,
+		Marker:     AzureMarkerAlloc,
+		Allocation: parsed.GetAtfAllocationByNet(netstring),
+	}
+	if row.Allocation == nil {
+		row.Marker = AzureMarkerFree
+		row.Allocation = &atf.Allocation{}
+	}
+	if row.Allocation.IsReserved {
+		row.Marker = AzureMarkerReserved
+	}
+	if _, ipnet, err := net.ParseCIDR(netstring); err == nil {
+		row.Hosts = netcalc.UsableHosts(ipnet, parsed.Provider).String()
+	}
+
+	cmr.printValues(row)
+
+	if subBlock := parsed.GetPoolByNet(netstring); subBlock != nil {
+		printBlocks(cmr, parsed, subBlock.FindAllAllocations(), depth+1, free)
+	}
+}
+
+func (cmr *ColumnMarkdownRenderer) PrintFreeRange(first, last net.IP, addresses *big.Int, depth int) {
+	cmr.printValues(&MarkdownRow{
+		Net:        freeRangeText(first, last, addresses),
+		Depth:      depth,
+		Marker:     AzureMarkerFree,
+		Allocation: &atf.Allocation{},
+	})
+}
+
+func (cmr *ColumnMarkdownRenderer) printValues(row *MarkdownRow) {
+	values := make([]string, 0, len(cmr.columns))
+	for _, column := range cmr.columns {
+		// a pipe would end the cell early
+		values = append(values, strings.ReplaceAll(column.Value(row), "|", "\\|"))
+	}
+	cmr.printRow(values)
+}
+
+func (cmr *ColumnMarkdownRenderer) printRow(values []string) {
+	fmt.Fprintf(cmr.target, "|%s|\n", strings.Join(values, "|"))
+}
+
+// markdownLink links url with the given text, nothing is printed without url
+func markdownLink(text string, url string) string {
+	if url == "" {
+		return ""
+	}
+	return fmt.Sprintf("[%s](%s)", text, url)
+}
@@ -0,0 +1,100 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package render
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRenderPoolToMarkdown(t *testing.T) {
+	parsed := parseFixture(t, fixtureText)
+	out := &bytes.Buffer{}
+	RenderPoolToMarkdown(out, parsed, true)
+	expectOutput(t, out, `# test (10.42.0.0/22, with empty blocks)
+
+[//]: # (Generated by atfutil, DO NOT EDIT)
+
+|Alloc|Ident|Block|SubAllocation|Hosts|Subscription|Resource Group|VNET|Description|
+|-|-|-|-|-|-|-|-|-|
+|✅|first|10.42.0.0/24||254|sub-1|||a \| b|
+|⚠️|sub||10.42.0.0/26|62||||reserved|
+||||10.42.0.64/26|62|||||
+||||10.42.0.128/25|126|||||
+|||10.42.1.0/24||254|||||
+|✅|second|10.42.2.0/24||254|||||
+|||10.42.3.0/24||254|||||
+`)
+}
+
+func TestNewMarkdownRenderer(t *testing.T) {
+	tests := []struct {
+		columns  []string
+		expected string
+	}{
+		{[]string{"aws"}, `|Alloc|Ident|Block|SubAllocation|Hosts|CloudFormation|Git|Documentation|Description|
+|-|-|-|-|-|-|-|-|-|
+|✅|first|10.42.0.0/24||254||[repo](https://example.com/first.git)||a \| b|
+|⚠️|sub||10.42.0.0/26|62||||reserved|
+|✅|second|10.42.2.0/24||254|||||
+`},
+		{[]string{"ident", "cidr"}, `|Ident|CIDR|
+|-|-|
+|first|10.42.0.0/24|
+|sub|↳10.42.0.0/26|
+|second|10.42.2.0/24|
+`},
+		{[]string{"generic"}, `|Alloc|Ident|Block|SubAllocation|Hosts|Description|
+|-|-|-|-|-|-|
+|✅|first|10.42.0.0/24||254|a \| b|
+|⚠️|sub||10.42.0.0/26|62|reserved|
+|✅|second|10.42.2.0/24||254||
+`},
+	}
+	parsed := parseFixture(t, fixtureText)
+	for _, tt := range tests {
+		out := &bytes.Buffer{}
+		dmr, err := NewMarkdownRenderer(out, tt.columns)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dmr.PrintHeader()
+		printBlocks(dmr, parsed, parsed.Pools[0].FindAllAllocations(), 0, FreeHidden)
+		expectOutput(t, out, tt.expected)
+	}
+
+	if _, err := NewMarkdownRenderer(&bytes.Buffer{}, []string{"ident", "nope"}); err == nil {
+		t.Error("expected an error for an unknown column")
+	}
+}
+
+func TestMarkdownColumnSets(t *testing.T) {
+	// the default renderers can't fail because every set only names known
+	// columns
+	for name, set := range MarkdownColumnSets {
+		for _, column := range set {
+			if _, ok := MarkdownColumns[column]; !ok {
+				t.Errorf("column set %s has unknown column %s", name, column)
+			}
+		}
+	}
+}
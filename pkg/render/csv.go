@@ -0,0 +1,114 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package render
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"atfutil/pkg/atf"
+	"atfutil/pkg/netcalc"
+	"atfutil/pkg/netpool"
+)
+
+var csvHeader = []string{
+	"Depth", "Block", "Parent", "Ident", "Status", "Addresses", "First Usable", "Last Usable", "Description",
+	"Subscription", "Resource Group", "VNET", "CloudFormation URL", "Git", "Documentation", "Usable Hosts",
+}
+
+// RenderPoolToCSV writes one row per block, separated by comma (e.g. ',' or
+// '\t'). Free blocks are only included with includeFree.
+func RenderPoolToCSV(target io.Writer, parsed *netpool.ParsedATF, includeFree bool, comma rune) error {
+	w := csv.NewWriter(target)
+	w.Comma = comma
+
+	if err := w.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, pool := range parsed.Pools {
+		if err := writeCSVBlocks(w, parsed, pool, 0, includeFree); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func writeCSVBlocks(w *csv.Writer, parsed *netpool.ParsedATF, pool *netcalc.IPNetPool, depth int, includeFree bool) error {
+	for _, block := range pool.FindAllAllocations() {
+		netstring := block.Net.String()
+		var alloc *atf.Allocation
+		if block.Alloc {
+			alloc = parsed.GetAtfAllocationByNet(netstring)
+		}
+		if alloc == nil && !includeFree {
+			continue
+		}
+
+		first, last := netcalc.UsableRange(block.Net)
+		row := []string{
+			strconv.Itoa(depth),
+			netstring,
+			pool.Super().String(),
+			"",
+			blockStatus(alloc),
+			netcalc.AddressCount(block.Net).String(),
+			first.String(),
+			last.String(),
+		}
+		if alloc != nil {
+			ref := alloc.Reference
+			row[3] = alloc.Ident
+			row = append(row,
+				alloc.Description,
+				ref.Azure.Subscription,
+				ref.Azure.ResourceGroup,
+				ref.Azure.VirtualNetwork,
+				ref.AWS.CloudFormationURL,
+				stringValue(ref.Git),
+				stringValue(ref.DocumentationURI),
+			)
+		} else {
+			row = append(row, make([]string, len(csvHeader)-len(row)-1)...)
+		}
+		row = append(row, netcalc.UsableHosts(block.Net, parsed.Provider).String())
+		if err := w.Write(row); err != nil {
+			return err
+		}
+
+		if subPool := parsed.GetPoolByNet(netstring); alloc != nil && subPool != nil {
+			if err := writeCSVBlocks(w, parsed, subPool, depth+1, includeFree); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// stringValue returns the string s points to, or "" for nil
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,56 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package render
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRenderPoolToCSV(t *testing.T) {
+	parsed := parseFixture(t, fixtureText)
+	out := &bytes.Buffer{}
+	if err := RenderPoolToCSV(out, parsed, true, ','); err != nil {
+		t.Fatal(err)
+	}
+	expectOutput(t, out, `Depth,Block,Parent,Ident,Status,Addresses,First Usable,Last Usable,Description,Subscription,Resource Group,VNET,CloudFormation URL,Git,Documentation,Usable Hosts
+0,10.42.0.0/24,10.42.0.0/22,first,allocated,256,10.42.0.1,10.42.0.254,a | b,sub-1,,,,https://example.com/first.git,,254
+1,10.42.0.0/26,10.42.0.0/24,sub,reserved,64,10.42.0.1,10.42.0.62,reserved,,,,,,,62
+1,10.42.0.64/26,10.42.0.0/24,,free,64,10.42.0.65,10.42.0.126,,,,,,,,62
+1,10.42.0.128/25,10.42.0.0/24,,free,128,10.42.0.129,10.42.0.254,,,,,,,,126
+0,10.42.1.0/24,10.42.0.0/22,,free,256,10.42.1.1,10.42.1.254,,,,,,,,254
+0,10.42.2.0/24,10.42.0.0/22,second,allocated,256,10.42.2.1,10.42.2.254,,,,,,,,254
+0,10.42.3.0/24,10.42.0.0/22,,free,256,10.42.3.1,10.42.3.254,,,,,,,,254
+`)
+}
+
+func TestRenderPoolToCSV_TSVWithoutFree(t *testing.T) {
+	parsed := parseFixture(t, fixtureText)
+	out := &bytes.Buffer{}
+	if err := RenderPoolToCSV(out, parsed, false, '\t'); err != nil {
+		t.Fatal(err)
+	}
+	expectOutput(t, out, "Depth\tBlock\tParent\tIdent\tStatus\tAddresses\tFirst Usable\tLast Usable\tDescription\tSubscription\tResource Group\tVNET\tCloudFormation URL\tGit\tDocumentation\tUsable Hosts\n"+
+		"0\t10.42.0.0/24\t10.42.0.0/22\tfirst\tallocated\t256\t10.42.0.1\t10.42.0.254\ta | b\tsub-1\t\t\t\thttps://example.com/first.git\t\t254\n"+
+		"1\t10.42.0.0/26\t10.42.0.0/24\tsub\treserved\t64\t10.42.0.1\t10.42.0.62\treserved\t\t\t\t\t\t\t62\n"+
+		"0\t10.42.2.0/24\t10.42.0.0/22\tsecond\tallocated\t256\t10.42.2.1\t10.42.2.254\t\t\t\t\t\t\t\t254\n")
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package render
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+
+	"atfutil/pkg/cidr"
+	"atfutil/pkg/netcalc"
+	"atfutil/pkg/netpool"
+)
+
+// FreeMode controls how markdown renderers print free space
+type FreeMode int
+
+const (
+	// FreeHidden leaves out free blocks at every level
+	FreeHidden FreeMode = iota
+	// FreeBlocks prints every free block as its own row
+	FreeBlocks
+	// FreeRanges collapses consecutive free blocks into one row
+	FreeRanges
+)
+
+// FreeModeFor returns the mode matching the --all-blocks flag
+func FreeModeFor(includeFree bool) FreeMode {
+	if includeFree {
+		return FreeBlocks
+	}
+	return FreeHidden
+}
+
+// printBlocks prints the given blocks of one pool with dmr
+func printBlocks(dmr MarkdownRenderer, parsed *netpool.ParsedATF, blocks []*netcalc.Block, depth int, free FreeMode) {
+	run := make([]*netcalc.Block, 0)
+	flush := func() {
+		if len(run) == 1 {
+			dmr.PrintAllocation(parsed, run[0].Net.String(), depth, free)
+		} else if len(run) > 1 {
+			first, _ := cidr.AddressRange(run[0].Net)
+			_, last := cidr.AddressRange(run[len(run)-1].Net)
+			addresses := new(big.Int)
+			for _, block := range run {
+				addresses.Add(addresses, netcalc.AddressCount(block.Net))
+			}
+			dmr.PrintFreeRange(first, last, addresses, depth)
+		}
+		run = run[:0]
+	}
+
+	for _, block := range blocks {
+		isFree := !block.Alloc || parsed.GetAtfAllocationByNet(block.Net.String()) == nil
+		switch {
+		case !isFree:
+			flush()
+			dmr.PrintAllocation(parsed, block.Net.String(), depth, free)
+		case free == FreeRanges:
+			run = append(run, block)
+		case free == FreeBlocks:
+			dmr.PrintAllocation(parsed, block.Net.String(), depth, free)
+		}
+	}
+	flush()
+}
+
+// freeRangeText describes a range of free addresses
+func freeRangeText(first, last net.IP, addresses *big.Int) string {
+	return fmt.Sprintf("free range %s–%s (%s addresses)", first, last, addresses)
+}
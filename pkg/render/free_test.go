@@ -0,0 +1,89 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package render
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRenderPoolToMarkdownWith_FreeModes(t *testing.T) {
+	tests := []struct {
+		name     string
+		free     FreeMode
+		expected string
+	}{
+		{"hidden", FreeModeFor(false), `# test (10.42.0.0/22)
+
+[//]: # (Generated by atfutil, DO NOT EDIT)
+
+|Alloc|Ident|Block|SubAllocation|Hosts|Description|
+|-|-|-|-|-|-|
+|✅|first|10.42.0.0/24||254|a \| b|
+|⚠️|sub||10.42.0.0/26|62|reserved|
+|✅|second|10.42.2.0/24||254||
+`},
+		// the free blocks in first are consecutive and collapse, those at the
+		// top level are separated by second
+		{"ranges", FreeRanges, `# test (10.42.0.0/22, with empty blocks)
+
+[//]: # (Generated by atfutil, DO NOT EDIT)
+
+|Alloc|Ident|Block|SubAllocation|Hosts|Description|
+|-|-|-|-|-|-|
+|✅|first|10.42.0.0/24||254|a \| b|
+|⚠️|sub||10.42.0.0/26|62|reserved|
+||||free range 10.42.0.64–10.42.0.255 (192 addresses)|||
+|||10.42.1.0/24||254||
+|✅|second|10.42.2.0/24||254||
+|||10.42.3.0/24||254||
+`},
+	}
+	parsed := parseFixture(t, fixtureText)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			dmr, err := NewMarkdownRenderer(out, []string{"generic"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			RenderPoolToMarkdownWith(out, parsed, tt.free, dmr)
+			expectOutput(t, out, tt.expected)
+		})
+	}
+}
+
+func TestRenderPoolToMarkdownWith_FreeRangeTopLevel(t *testing.T) {
+	parsed := parseFixture(t, "superBlock: 10.42.0.0/22\nallocations:\n- cidr: 10.42.0.0/24\n  ident: only\n")
+	out := &bytes.Buffer{}
+	dmr, err := NewMarkdownRenderer(out, []string{"ident", "cidr"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dmr.PrintHeader()
+	printBlocks(dmr, parsed, parsed.Pools[0].FindAllAllocations(), 0, FreeRanges)
+	expectOutput(t, out, `|Ident|CIDR|
+|-|-|
+|only|10.42.0.0/24|
+||free range 10.42.1.0–10.42.3.255 (768 addresses)|
+`)
+}
@@ -0,0 +1,273 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package render
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strconv"
+
+	"atfutil/pkg/atf"
+	"atfutil/pkg/netcalc"
+	"atfutil/pkg/netpool"
+)
+
+type htmlReport struct {
+	Title       string
+	Superblocks []*htmlSuperblock
+	Rows        []*htmlRow
+}
+
+type htmlSuperblock struct {
+	CIDR  string
+	Usage *netpool.Usage
+}
+
+type htmlRow struct {
+	Path         string
+	Depth        int
+	HasChildren  bool
+	IsSuperblock bool
+	Status       string
+	Marker       string
+	CIDR         string
+	Addresses    string
+	UsableHosts  string
+	Allocation   *atf.Allocation
+}
+
+// RenderPoolToHTML writes a self-contained HTML page with a utilisation
+// summary and a collapsible, filterable table of all blocks. Free blocks are
+// only included with includeFree.
+func RenderPoolToHTML(target io.Writer, parsed *netpool.ParsedATF, includeFree bool) error {
+	report := &htmlReport{
+		Title:       superblockList(parsed),
+		Superblocks: make([]*htmlSuperblock, 0, len(parsed.Pools)),
+		Rows:        make([]*htmlRow, 0),
+	}
+	if parsed.File.Name != nil {
+		report.Title = fmt.Sprintf("%s (%s)", *parsed.File.Name, superblockList(parsed))
+	}
+	for i, pool := range parsed.Pools {
+		report.Superblocks = append(report.Superblocks, &htmlSuperblock{
+			CIDR:  pool.Super().String(),
+			Usage: parsed.Usage(pool),
+		})
+
+		// every superblock gets a row to collapse all its blocks
+		path := strconv.Itoa(i)
+		children := htmlRows(parsed, pool, path+".", 0, includeFree)
+		report.Rows = append(report.Rows, &htmlRow{
+			Path:         path,
+			HasChildren:  len(children) > 0,
+			IsSuperblock: true,
+			CIDR:         pool.Super().String(),
+			Allocation:   &atf.Allocation{},
+		})
+		report.Rows = append(report.Rows, children...)
+	}
+
+	return htmlTmpl.Execute(target, report)
+}
+
+func htmlRows(parsed *netpool.ParsedATF, pool *netcalc.IPNetPool, path string, depth int, includeFree bool) []*htmlRow {
+	rows := make([]*htmlRow, 0)
+	for i, block := range pool.FindAllAllocations() {
+		netstring := block.Net.String()
+		var alloc *atf.Allocation
+		if block.Alloc {
+			alloc = parsed.GetAtfAllocationByNet(netstring)
+		}
+		if alloc == nil && !includeFree {
+			continue
+		}
+
+		row := &htmlRow{
+			Path:        path + strconv.Itoa(i),
+			Depth:       depth,
+			Status:      blockStatus(alloc),
+			CIDR:        netstring,
+			Addresses:   netcalc.AddressCount(block.Net).String(),
+			UsableHosts: netcalc.UsableHosts(block.Net, parsed.Provider).String(),
+			Allocation:  alloc,
+		}
+		switch row.Status {
+		case StatusAllocated:
+			row.Marker = AzureMarkerAlloc
+		case StatusReserved:
+			row.Marker = AzureMarkerReserved
+		default:
+			row.Marker = AzureMarkerFree
+			row.Allocation = &atf.Allocation{}
+		}
+		rows = append(rows, row)
+
+		if subPool := parsed.GetPoolByNet(netstring); alloc != nil && subPool != nil {
+			children := htmlRows(parsed, subPool, row.Path+".", depth+1, includeFree)
+			row.HasChildren = len(children) > 0
+			rows = append(rows, children...)
+		}
+	}
+	return rows
+}
+
+var htmlTmpl = template.Must(template.New("html").Funcs(template.FuncMap{
+	// share formats the percentage of addresses in the given state
+	"share": func(usage *netpool.Usage, status string) string {
+		switch status {
+		case "used":
+			return fmt.Sprintf("%.1f", usage.UsedPercent())
+		case StatusAllocated:
+			return fmt.Sprintf("%.2f", usage.Percent(usage.Allocated))
+		case StatusReserved:
+			return fmt.Sprintf("%.2f", usage.Percent(usage.Reserved))
+		default:
+			return fmt.Sprintf("%.2f", usage.Percent(usage.Free))
+		}
+	},
+}).Parse(htmlTemplate))
+
+const htmlTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="generator" content="atfutil">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; }
+th, td { padding: 0.25em 0.6em; text-align: left; border-bottom: 1px solid #ddd; }
+td.num { text-align: right; font-variant-numeric: tabular-nums; }
+tr.allocated td.status { background: #d4edda; }
+tr.reserved td.status { background: #fff3cd; }
+tr.free td.status { background: #eee; color: #666; }
+tr.free { color: #666; }
+tr.superblock th { background: #f5f5f5; }
+tr.hidden { display: none; }
+button.toggle { border: none; background: none; cursor: pointer; width: 1.5em; }
+.bar { display: flex; width: 40em; height: 1em; border: 1px solid #aaa; }
+.bar .allocated { background: #28a745; }
+.bar .reserved { background: #ffc107; }
+.bar .free { background: #eee; }
+#filter { margin: 1em 0; padding: 0.3em; width: 30em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<!-- Generated by atfutil, DO NOT EDIT -->
+<table class="summary">
+<tr><th>Superblock</th><th>Addresses</th><th>Allocated</th><th>Reserved</th><th>Free</th><th>Used</th><th></th></tr>
+{{- range .Superblocks}}
+<tr>
+<td>{{.CIDR}}</td>
+<td class="num">{{.Usage.Total}}</td>
+<td class="num">{{.Usage.Allocated}}</td>
+<td class="num">{{.Usage.Reserved}}</td>
+<td class="num">{{.Usage.Free}}</td>
+<td class="num">{{share .Usage "used"}}%</td>
+<td><div class="bar"><span class="allocated" style="width: {{share .Usage "allocated"}}%"></span><span class="reserved" style="width: {{share .Usage "reserved"}}%"></span><span class="free" style="width: {{share .Usage "free"}}%"></span></div></td>
+</tr>
+{{- end}}
+</table>
+<input id="filter" type="search" placeholder="Filter by ident or description">
+<table id="blocks">
+<thead>
+<tr><th></th><th>Alloc</th><th>Ident</th><th>Block</th><th>Addresses</th><th>Usable Hosts</th><th>Subscription</th><th>Resource Group</th><th>VNET</th><th>Description</th></tr>
+</thead>
+<tbody>
+{{- range .Rows}}
+{{- if .IsSuperblock}}
+<tr class="superblock" data-path="{{.Path}}" data-search="">
+<td>{{if .HasChildren}}<button class="toggle" aria-expanded="true">&#9662;</button>{{end}}</td>
+<th colspan="9">{{.CIDR}}</th>
+</tr>
+{{- else}}
+<tr class="{{.Status}}" data-path="{{.Path}}" data-search="{{.Allocation.Ident}} {{.Allocation.Description}}">
+<td>{{if .HasChildren}}<button class="toggle" aria-expanded="true">&#9662;</button>{{end}}</td>
+<td class="status" title="{{.Status}}">{{.Marker}} {{.Status}}</td>
+<td>{{.Allocation.Ident}}</td>
+<td style="padding-left: {{.Depth}}.5em">{{.CIDR}}</td>
+<td class="num">{{.Addresses}}</td>
+<td class="num">{{.UsableHosts}}</td>
+<td>{{.Allocation.Reference.Azure.Subscription}}</td>
+<td>{{.Allocation.Reference.Azure.ResourceGroup}}</td>
+<td>{{.Allocation.Reference.Azure.VirtualNetwork}}</td>
+<td>{{.Allocation.Description}}</td>
+</tr>
+{{- end}}
+{{- end}}
+</tbody>
+</table>
+<script>
+(function () {
+  var rows = Array.prototype.slice.call(document.querySelectorAll("#blocks tbody tr"));
+  var collapsed = {};
+
+  function isCollapsed(path) {
+    var parts = path.split(".");
+    for (var i = 1; i < parts.length; i++) {
+      if (collapsed[parts.slice(0, i).join(".")]) {
+        return true;
+      }
+    }
+    return false;
+  }
+
+  function update() {
+    var filter = document.getElementById("filter").value.toLowerCase();
+    var visible = {};
+    rows.forEach(function (row) {
+      if (filter === "" || row.dataset.search.toLowerCase().indexOf(filter) !== -1) {
+        // keep the parents of a match visible
+        var parts = row.dataset.path.split(".");
+        for (var i = 1; i <= parts.length; i++) {
+          visible[parts.slice(0, i).join(".")] = true;
+        }
+      }
+    });
+    rows.forEach(function (row) {
+      var path = row.dataset.path;
+      var hidden = !visible[path] || (filter === "" && isCollapsed(path));
+      row.classList.toggle("hidden", hidden);
+    });
+  }
+
+  rows.forEach(function (row) {
+    var button = row.querySelector("button.toggle");
+    if (!button) {
+      return;
+    }
+    button.addEventListener("click", function () {
+      var path = row.dataset.path;
+      collapsed[path] = !collapsed[path];
+      button.setAttribute("aria-expanded", String(!collapsed[path]));
+      button.innerHTML = collapsed[path] ? "&#9656;" : "&#9662;";
+      update();
+    });
+  });
+  document.getElementById("filter").addEventListener("input", update);
+})();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,74 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package render
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRenderPoolToHTML(t *testing.T) {
+	parsed := parseFixture(t, strings.Replace(fixtureText, "a | b", "a <b>", 1))
+	out := &bytes.Buffer{}
+	if err := RenderPoolToHTML(out, parsed, true); err != nil {
+		t.Fatal(err)
+	}
+	html := out.String()
+	for _, want := range []string{
+		"<title>test (10.42.0.0/22)</title>",
+		// usage summary of the superblock
+		`<td class="num">1024</td>
+<td class="num">512</td>
+<td class="num">0</td>
+<td class="num">512</td>
+<td class="num">50.0%</td>`,
+		// the description is escaped
+		`<tr class="allocated" data-path="0.0" data-search="first a &lt;b&gt;">`,
+		"<td>a &lt;b&gt;</td>",
+		// nested blocks are indented and can be collapsed with their parent
+		`<tr class="reserved" data-path="0.0.0" data-search="sub reserved">`,
+		`<td style="padding-left: 1.5em">10.42.0.0/26</td>`,
+		`<tr class="free" data-path="0.0.2" data-search=" ">`,
+		`<td style="padding-left: 1.5em">10.42.0.128/25</td>`,
+		`<tr class="free" data-path="0.3" data-search=" ">`,
+		`<td style="padding-left: 0.5em">10.42.3.0/24</td>`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("missing in the page:\n%s", want)
+		}
+	}
+	if strings.Contains(html, "a <b>") {
+		t.Error("the description is not escaped")
+	}
+
+	out.Reset()
+	if err := RenderPoolToHTML(out, parsed, false); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), `<tr class="free"`) {
+		t.Error("free blocks are rendered without includeFree")
+	}
+	if !strings.Contains(out.String(), `data-search="second "`) {
+		t.Error("second block is missing without includeFree")
+	}
+}
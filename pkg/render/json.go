@@ -0,0 +1,104 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package render
+
+import (
+	"encoding/json"
+	"io"
+
+	"atfutil/pkg/atf"
+	"atfutil/pkg/netcalc"
+	"atfutil/pkg/netpool"
+)
+
+// JSONDocument is the root of the JSON rendering of a file
+type JSONDocument struct {
+	Name        *string           `json:"name,omitempty"`
+	Superblocks []*JSONSuperblock `json:"superblocks"`
+}
+
+// JSONSuperblock holds the blocks of one superblock
+type JSONSuperblock struct {
+	CIDR   string       `json:"cidr"`
+	Blocks []*JSONBlock `json:"blocks"`
+}
+
+// JSONBlock is an allocated or free block, Blocks holds the blocks of its
+// suballocations
+type JSONBlock struct {
+	CIDR        string         `json:"cidr"`
+	Depth       int            `json:"depth"`
+	Free        bool           `json:"free"`
+	Reserved    bool           `json:"reserved"`
+	UsableHosts string         `json:"usableHosts"`
+	Ident       string         `json:"ident,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Reference   *atf.Reference `json:"ref,omitempty"`
+	Blocks      []*JSONBlock   `json:"blocks,omitempty"`
+}
+
+// RenderPoolToJSON writes all blocks with their metadata, free blocks are only
+// included with includeFree
+func RenderPoolToJSON(target io.Writer, parsed *netpool.ParsedATF, includeFree bool) error {
+	doc := &JSONDocument{
+		Name:        parsed.File.Name,
+		Superblocks: make([]*JSONSuperblock, 0, len(parsed.Pools)),
+	}
+	for _, pool := range parsed.Pools {
+		doc.Superblocks = append(doc.Superblocks, &JSONSuperblock{
+			CIDR:   pool.Super().String(),
+			Blocks: jsonBlocks(parsed, pool, 0, includeFree),
+		})
+	}
+
+	enc := json.NewEncoder(target)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+func jsonBlocks(parsed *netpool.ParsedATF, pool *netcalc.IPNetPool, depth int, includeFree bool) []*JSONBlock {
+	blocks := make([]*JSONBlock, 0)
+	for _, block := range pool.FindAllAllocations() {
+		netstring := block.Net.String()
+		if !block.Alloc && !includeFree {
+			continue
+		}
+		jsonBlock := &JSONBlock{
+			CIDR:  netstring,
+			Depth: depth,
+			Free:  !block.Alloc,
+			// a string, IPv6 networks easily exceed the range of JSON numbers
+			UsableHosts: netcalc.UsableHosts(block.Net, parsed.Provider).String(),
+		}
+		if alloc := parsed.GetAtfAllocationByNet(netstring); block.Alloc && alloc != nil {
+			jsonBlock.Reserved = alloc.IsReserved
+			jsonBlock.Ident = alloc.Ident
+			jsonBlock.Description = alloc.Description
+			jsonBlock.Reference = &alloc.Reference
+		}
+		if subPool := parsed.GetPoolByNet(netstring); block.Alloc && subPool != nil {
+			jsonBlock.Blocks = jsonBlocks(parsed, subPool, depth+1, includeFree)
+		}
+		blocks = append(blocks, jsonBlock)
+	}
+	return blocks
+}
@@ -0,0 +1,91 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// jsonTree writes the blocks with their nested blocks in brackets, free
+// blocks are marked with a *
+func jsonTree(blocks []*JSONBlock) string {
+	cidrs := make([]string, 0, len(blocks))
+	for _, block := range blocks {
+		cidr := block.CIDR
+		if block.Free {
+			cidr += "*"
+		}
+		if len(block.Blocks) > 0 {
+			cidr += "(" + jsonTree(block.Blocks) + ")"
+		}
+		cidrs = append(cidrs, cidr)
+	}
+	return strings.Join(cidrs, " ")
+}
+
+func TestRenderPoolToJSON(t *testing.T) {
+	tests := []struct {
+		includeFree bool
+		want        string
+	}{
+		{true, "10.42.0.0/24(10.42.0.0/26 10.42.0.64/26* 10.42.0.128/25*) 10.42.1.0/24* 10.42.2.0/24 10.42.3.0/24*"},
+		{false, "10.42.0.0/24(10.42.0.0/26) 10.42.2.0/24"},
+	}
+	parsed := parseFixture(t, fixtureText)
+	for _, tt := range tests {
+		out := &bytes.Buffer{}
+		if err := RenderPoolToJSON(out, parsed, tt.includeFree); err != nil {
+			t.Fatal(err)
+		}
+		doc := &JSONDocument{}
+		if err := json.Unmarshal(out.Bytes(), doc); err != nil {
+			t.Fatal(err)
+		}
+		keys := map[string]json.RawMessage{}
+		if err := json.Unmarshal(out.Bytes(), &keys); err != nil {
+			t.Fatal(err)
+		}
+		if len(keys) != 2 || keys["name"] == nil || keys["superblocks"] == nil {
+			t.Errorf("expected only name and superblocks at the top level, got %s", out)
+		}
+
+		if doc.Name == nil || *doc.Name != "test" || len(doc.Superblocks) != 1 || doc.Superblocks[0].CIDR != "10.42.0.0/22" {
+			t.Fatalf("unexpected name %v or superblocks %+v", doc.Name, doc.Superblocks)
+		}
+		blocks := doc.Superblocks[0].Blocks
+		if got := jsonTree(blocks); got != tt.want {
+			t.Errorf("blocks with includeFree %v:\n%s\nwant:\n%s", tt.includeFree, got, tt.want)
+		}
+
+		first := blocks[0]
+		if first.Ident != "first" || first.Description != "a | b" || first.Reference == nil || first.Reference.Azure.Subscription != "sub-1" {
+			t.Errorf("unexpected metadata of first: %+v", first)
+		}
+		sub := first.Blocks[0]
+		if sub.Ident != "sub" || !sub.Reserved || sub.Depth != 1 || sub.UsableHosts != "62" {
+			t.Errorf("unexpected nested block: %+v", sub)
+		}
+	}
+}
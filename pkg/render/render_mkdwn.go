@@ -24,47 +24,62 @@ package render
 import (
 	"fmt"
 	"io"
+	"math/big"
+	"net"
+	"strings"
 
-	"atfutil/pkg/atf"
 	"atfutil/pkg/netpool"
 )
 
 func RenderPoolToMarkdown(target io.Writer, parsed *netpool.ParsedATF, includeFree bool) {
-	ipToAllocMap := make(map[string]*atf.Allocation)
-
-	for _, allocation := range parsed.File.Allocations {
-		ipToAllocMap[allocation.Network.String()] = allocation
-		for _, subAlloc := range allocation.SubAlloc {
-			ipToAllocMap[subAlloc.Network.String()] = subAlloc
-		}
-	}
+	RenderPoolToMarkdownWith(target, parsed, FreeModeFor(includeFree), NewDefaultAzureMarkdownRenderer(target))
+}
 
+// RenderPoolToMarkdownWith renders the table with the given renderer, which
+// has to write to target as well
+func RenderPoolToMarkdownWith(target io.Writer, parsed *netpool.ParsedATF, free FreeMode, dmr MarkdownRenderer) {
+	includeFree := free != FreeHidden
 	if parsed.File.Name == nil {
 		addedStr := " "
 		if includeFree {
 			addedStr += "(with empty blocks)"
 		}
-		fmt.Fprintf(target, "# %s%s\n\n", parsed.File.Superblock.String(), addedStr)
+		fmt.Fprintf(target, "# %s%s\n\n", superblockList(parsed), addedStr)
 	} else {
 		addedStr := ""
 		if includeFree {
 			addedStr += ", with empty blocks"
 		}
-		fmt.Fprintf(target, "# %s (%s%s)\n\n", *parsed.File.Name, parsed.File.Superblock.String(), addedStr)
+		fmt.Fprintf(target, "# %s (%s%s)\n\n", *parsed.File.Name, superblockList(parsed), addedStr)
 	}
 
 	fmt.Fprintf(target, "[//]: # (%s)\n\n", "Generated by atfutil, DO NOT EDIT")
 
-	var dmr MarkdownRenderer = NewDefaultAzureMarkdownRenderer(target)
-
-	dmr.PrintHeader()
+	// files with several superblocks get one table per superblock
+	for i, pool := range parsed.Pools {
+		if len(parsed.Pools) > 1 {
+			if i > 0 {
+				fmt.Fprintln(target)
+			}
+			fmt.Fprintf(target, "## %s\n\n", pool.Super().String())
+		}
+		dmr.PrintHeader()
+		printBlocks(dmr, parsed, pool.FindAllAllocations(), 0, free)
+	}
+}
 
-	for _, block := range parsed.Pool.FindAllAllocations() {
-		dmr.PrintAllocation(parsed, block.Net.String(), false, includeFree)
+// superblockList lists all superblocks of the file
+func superblockList(parsed *netpool.ParsedATF) string {
+	supers := make([]string, 0, len(parsed.Pools))
+	for _, pool := range parsed.Pools {
+		supers = append(supers, pool.Super().String())
 	}
+	return strings.Join(supers, ", ")
 }
 
 type MarkdownRenderer interface {
 	PrintHeader()
-	PrintAllocation(parsed *netpool.ParsedATF, netstring string, isSub bool, includeFree bool)
+	PrintAllocation(parsed *netpool.ParsedATF, netstring string, depth int, free FreeMode)
+	// PrintFreeRange prints consecutive free blocks as one row
+	PrintFreeRange(first, last net.IP, addresses *big.Int, depth int)
 }
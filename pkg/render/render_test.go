@@ -0,0 +1,70 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package render
+
+import (
+	"bytes"
+	"testing"
+
+	"atfutil/pkg/atf"
+	"atfutil/pkg/netpool"
+)
+
+// fixtureText has free space at the top level and inside an allocation
+const fixtureText = `name: test
+superBlock: 10.42.0.0/22
+allocations:
+- cidr: 10.42.0.0/24
+  ident: first
+  description: a | b
+  ref:
+    azure:
+      subscription: sub-1
+    git: https://example.com/first.git
+  subAlloc:
+  - cidr: 10.42.0.0/26
+    ident: sub
+    reserved: true
+    description: reserved
+- cidr: 10.42.2.0/24
+  ident: second
+`
+
+func parseFixture(t *testing.T, text string) *netpool.ParsedATF {
+	t.Helper()
+	doc, err := atf.ParseDocument([]byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := netpool.FromAtf(doc.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func expectOutput(t *testing.T, actual *bytes.Buffer, expected string) {
+	t.Helper()
+	if actual.String() != expected {
+		t.Errorf("unexpected output\nexpected:\n%s\nactual:\n%s", expected, actual.String())
+	}
+}
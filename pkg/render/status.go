@@ -0,0 +1,46 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package render
+
+import (
+	"atfutil/pkg/atf"
+)
+
+// plain text states of a block, the same states the markdown renderer shows
+// as AzureMarkerAlloc, AzureMarkerReserved and AzureMarkerFree
+const (
+	StatusAllocated = "allocated"
+	StatusReserved  = "reserved"
+	StatusFree      = "free"
+)
+
+// blockStatus returns the state of a block, alloc is nil for free blocks
+func blockStatus(alloc *atf.Allocation) string {
+	switch {
+	case alloc == nil:
+		return StatusFree
+	case alloc.IsReserved:
+		return StatusReserved
+	default:
+		return StatusAllocated
+	}
+}
@@ -0,0 +1,181 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"math/big"
+	"net"
+
+	"atfutil/pkg/atf"
+	"atfutil/pkg/netcalc"
+	"atfutil/pkg/netpool"
+)
+
+const (
+	svgWidth     = 1200
+	svgMargin    = 10
+	svgRowHeight = 36
+	// rough width of a character at the label font size, used to decide
+	// whether a label fits into its block
+	svgCharWidth = 7
+)
+
+var svgColors = map[string]string{
+	StatusAllocated: "#28a745",
+	StatusReserved:  "#ffc107",
+	StatusFree:      "#e9ecef",
+}
+
+// svgRect is one block in the map, X and Width are in pixels
+type svgRect struct {
+	X      float64
+	Width  float64
+	Row    int
+	Status string
+	CIDR   string
+	Ident  string
+	Title  string
+}
+
+// RenderPoolToSVG draws each superblock as a bar with every block placed and
+// sized in proportion to its address range, suballocations are drawn in a
+// row below their parent and superblocks below each other
+func RenderPoolToSVG(target io.Writer, parsed *netpool.ParsedATF) error {
+	title := superblockList(parsed)
+	if parsed.File.Name != nil {
+		title = fmt.Sprintf("%s (%s)", *parsed.File.Name, title)
+	}
+
+	rows := 0
+	rects := make([]*svgRect, 0)
+	for _, pool := range parsed.Pools {
+		super := pool.Super()
+		rects = append(rects, &svgRect{
+			Row:   rows,
+			Width: svgWidth,
+			CIDR:  super.String(),
+			Ident: stringValue(parsed.File.Name),
+			Title: super.String(),
+		})
+		depth := 0
+		for _, rect := range svgRects(parsed, pool, super, 1) {
+			if rect.Row > depth {
+				depth = rect.Row
+			}
+			rect.Row += rows
+			rects = append(rects, rect)
+		}
+		rows += depth + 1
+	}
+	height := 2*svgMargin + (rows+1)*svgRowHeight
+
+	w := bufio.NewWriter(target)
+	fmt.Fprintf(w, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\" font-family=\"sans-serif\" font-size=\"11\">\n",
+		svgWidth+2*svgMargin, height, svgWidth+2*svgMargin, height)
+	fmt.Fprintf(w, "<!-- Generated by atfutil, DO NOT EDIT -->\n")
+	fmt.Fprintf(w, "<title>%s</title>\n", html.EscapeString(title))
+	for _, rect := range rects {
+		fill, ok := svgColors[rect.Status]
+		if !ok {
+			// superblocks
+			fill = "#ffffff"
+		}
+		writeSVGRect(w, rect, fill)
+	}
+
+	// legend
+	y := svgMargin + rows*svgRowHeight + svgRowHeight/3
+	x := svgMargin
+	for _, status := range []string{StatusAllocated, StatusReserved, StatusFree} {
+		fmt.Fprintf(w, "<rect x=\"%d\" y=\"%d\" width=\"12\" height=\"12\" fill=\"%s\" stroke=\"#666\"/>\n", x, y, svgColors[status])
+		fmt.Fprintf(w, "<text x=\"%d\" y=\"%d\">%s</text>\n", x+16, y+10, status)
+		x += 100
+	}
+	fmt.Fprintf(w, "</svg>\n")
+	return w.Flush()
+}
+
+func svgRects(parsed *netpool.ParsedATF, pool *netcalc.IPNetPool, super *net.IPNet, depth int) []*svgRect {
+	rects := make([]*svgRect, 0)
+	total := new(big.Float).SetInt(netcalc.AddressCount(super))
+	start := new(big.Int).SetBytes(super.IP.To16())
+
+	for _, block := range pool.FindAllAllocations() {
+		netstring := block.Net.String()
+		var alloc *atf.Allocation
+		if block.Alloc {
+			alloc = parsed.GetAtfAllocationByNet(netstring)
+		}
+
+		offset := new(big.Int).Sub(new(big.Int).SetBytes(block.Net.IP.To16()), start)
+		x, _ := new(big.Float).Quo(new(big.Float).SetInt(offset), total).Float64()
+		width, _ := new(big.Float).Quo(new(big.Float).SetInt(netcalc.AddressCount(block.Net)), total).Float64()
+
+		rect := &svgRect{
+			X:      x * svgWidth,
+			Width:  width * svgWidth,
+			Row:    depth,
+			Status: blockStatus(alloc),
+			CIDR:   netstring,
+			Title:  netstring + " " + blockStatus(alloc),
+		}
+		if alloc != nil {
+			rect.Ident = alloc.Ident
+			rect.Title = fmt.Sprintf("%s %s (%s)", netstring, alloc.Ident, rect.Status)
+			if alloc.Description != "" {
+				rect.Title += ": " + alloc.Description
+			}
+		}
+		rect.Title += fmt.Sprintf(", %s usable hosts", netcalc.UsableHosts(block.Net, parsed.Provider))
+		rects = append(rects, rect)
+
+		if subPool := parsed.GetPoolByNet(netstring); alloc != nil && subPool != nil {
+			rects = append(rects, svgRects(parsed, subPool, super, depth+1)...)
+		}
+	}
+	return rects
+}
+
+// writeSVGRect draws a block with a tooltip, ident and CIDR are only printed
+// when they fit into the block
+func writeSVGRect(w io.Writer, rect *svgRect, fill string) {
+	x := svgMargin + rect.X
+	y := svgMargin + rect.Row*svgRowHeight
+	fmt.Fprintf(w, "<g>\n<title>%s</title>\n", html.EscapeString(rect.Title))
+	fmt.Fprintf(w, "<rect x=\"%.2f\" y=\"%d\" width=\"%.2f\" height=\"%d\" fill=\"%s\" stroke=\"#666\" stroke-width=\"0.5\"/>\n",
+		x, y, rect.Width, svgRowHeight, fill)
+
+	lines := make([]string, 0, 2)
+	for _, label := range []string{rect.Ident, rect.CIDR} {
+		if label != "" && float64(len(label)*svgCharWidth+6) <= rect.Width {
+			lines = append(lines, label)
+		}
+	}
+	for i, line := range lines {
+		fmt.Fprintf(w, "<text x=\"%.2f\" y=\"%d\">%s</text>\n", x+3, y+14+i*13, html.EscapeString(line))
+	}
+	fmt.Fprintf(w, "</g>\n")
+}
@@ -0,0 +1,63 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package render
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRenderPoolToSVG(t *testing.T) {
+	parsed := parseFixture(t, fixtureText)
+	out := &bytes.Buffer{}
+	if err := RenderPoolToSVG(out, parsed); err != nil {
+		t.Fatal(err)
+	}
+	svg := out.String()
+	if !strings.HasPrefix(svg, `<svg xmlns="http://www.w3.org/2000/svg" width="1220" height="164"`) || !strings.HasSuffix(svg, "</svg>\n") {
+		t.Errorf("unexpected frame of the map:\n%s", svg)
+	}
+	for _, want := range []string{
+		// the superblock spans the whole width, every /24 a quarter of it
+		`<rect x="10.00" y="10" width="1200.00" height="36" fill="#ffffff"`,
+		`<title>10.42.0.0/24 first (allocated): a | b, 254 usable hosts</title>
+<rect x="10.00" y="46" width="300.00" height="36" fill="#28a745"`,
+		`<title>10.42.2.0/24 second (allocated), 254 usable hosts</title>
+<rect x="610.00" y="46" width="300.00" height="36" fill="#28a745"`,
+		// suballocations and their free blocks go in the row below
+		`<title>10.42.0.0/26 sub (reserved): reserved, 62 usable hosts</title>
+<rect x="10.00" y="82" width="75.00" height="36" fill="#ffc107"`,
+		`<title>10.42.0.128/25 free, 126 usable hosts</title>
+<rect x="160.00" y="82" width="150.00" height="36" fill="#e9ecef"`,
+		`<title>10.42.3.0/24 free, 254 usable hosts</title>
+<rect x="910.00" y="46" width="300.00" height="36" fill="#e9ecef"`,
+	} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("missing in the map:\n%s", want)
+		}
+	}
+	// a /26 is too narrow for its CIDR
+	if strings.Contains(svg, "<text x=\"88.00\"") {
+		t.Error("narrow free block is labelled")
+	}
+}
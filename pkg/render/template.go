@@ -0,0 +1,160 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package render
+
+import (
+	"io"
+	"strings"
+	"text/template"
+
+	"atfutil/pkg/atf"
+	"atfutil/pkg/netcalc"
+	"atfutil/pkg/netpool"
+)
+
+// TemplateData is the root object passed to a template
+type TemplateData struct {
+	// Name of the file, empty if it has none
+	Name string
+	// Superblock is the CIDR of the first superblock of the file
+	Superblock string
+	// Superblocks lists all superblocks of the file
+	Superblocks []*TemplateSuperblock
+	// IncludeFree is set when free blocks are rendered (--all-blocks)
+	IncludeFree bool
+	// Blocks lists all blocks in order, every block is directly followed by
+	// the blocks nested in it
+	Blocks []*TemplateBlock
+	// TopLevel lists the blocks directly in the superblocks, use Children to
+	// walk the tree
+	TopLevel []*TemplateBlock
+}
+
+// TemplateSuperblock is one superblock of the file
+type TemplateSuperblock struct {
+	CIDR string
+	// Blocks lists the blocks directly in the superblock
+	Blocks []*TemplateBlock
+}
+
+// TemplateBlock is an allocated or free block
+type TemplateBlock struct {
+	CIDR string
+	// Parent is the CIDR of the block this one is nested in, the superblock
+	// for top-level blocks
+	Parent string
+	// Depth is 0 for top-level blocks, 1 for their suballocations and so on
+	Depth int
+	// Status is one of "allocated", "reserved" and "free"
+	Status   string
+	Free     bool
+	Reserved bool
+	// Addresses is the number of addresses in the block, UsableHosts the
+	// number left after those the file's provider reserves
+	Addresses   string
+	UsableHosts string
+	FirstUsable string
+	LastUsable  string
+	// Allocation holds ident, description and references, all fields are
+	// empty for free blocks
+	Allocation *atf.Allocation
+	Reference  *atf.Reference
+	Children   []*TemplateBlock
+}
+
+var templateFuncs = template.FuncMap{
+	// value dereferences optional fields like .Reference.Git
+	"value":  stringValue,
+	"repeat": strings.Repeat,
+	"join":   strings.Join,
+	"upper":  strings.ToUpper,
+	"lower":  strings.ToLower,
+}
+
+// RenderPoolToTemplate renders the file with a text/template, see
+// TemplateData for the data passed to it. Free blocks are only included with
+// includeFree.
+func RenderPoolToTemplate(target io.Writer, parsed *netpool.ParsedATF, name string, text string, includeFree bool) error {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return err
+	}
+
+	data := &TemplateData{
+		Name:        stringValue(parsed.File.Name),
+		Superblock:  parsed.Pools[0].Super().String(),
+		Superblocks: make([]*TemplateSuperblock, 0, len(parsed.Pools)),
+		IncludeFree: includeFree,
+		Blocks:      make([]*TemplateBlock, 0),
+		TopLevel:    make([]*TemplateBlock, 0),
+	}
+	for _, pool := range parsed.Pools {
+		super := &TemplateSuperblock{
+			CIDR:   pool.Super().String(),
+			Blocks: data.addBlocks(parsed, pool, 0),
+		}
+		data.Superblocks = append(data.Superblocks, super)
+		data.TopLevel = append(data.TopLevel, super.Blocks...)
+	}
+
+	return tmpl.Execute(target, data)
+}
+
+func (data *TemplateData) addBlocks(parsed *netpool.ParsedATF, pool *netcalc.IPNetPool, depth int) []*TemplateBlock {
+	blocks := make([]*TemplateBlock, 0)
+	for _, block := range pool.FindAllAllocations() {
+		netstring := block.Net.String()
+		var alloc *atf.Allocation
+		if block.Alloc {
+			alloc = parsed.GetAtfAllocationByNet(netstring)
+		}
+		if alloc == nil && !data.IncludeFree {
+			continue
+		}
+
+		first, last := netcalc.UsableRange(block.Net)
+		tmplBlock := &TemplateBlock{
+			CIDR:        netstring,
+			Parent:      pool.Super().String(),
+			Depth:       depth,
+			Status:      blockStatus(alloc),
+			Free:        alloc == nil,
+			Addresses:   netcalc.AddressCount(block.Net).String(),
+			UsableHosts: netcalc.UsableHosts(block.Net, parsed.Provider).String(),
+			FirstUsable: first.String(),
+			LastUsable:  last.String(),
+			Allocation:  alloc,
+		}
+		if alloc == nil {
+			tmplBlock.Allocation = &atf.Allocation{}
+		}
+		tmplBlock.Reserved = tmplBlock.Allocation.IsReserved
+		tmplBlock.Reference = &tmplBlock.Allocation.Reference
+		blocks = append(blocks, tmplBlock)
+		data.Blocks = append(data.Blocks, tmplBlock)
+
+		if subPool := parsed.GetPoolByNet(netstring); alloc != nil && subPool != nil {
+			tmplBlock.Children = data.addBlocks(parsed, subPool, depth+1)
+		}
+	}
+	return blocks
+}
@@ -0,0 +1,69 @@
+/*
+ * Copyright 2023 Aurelia Schittler
+ *
+ * Licensed under the EUPL, Version 1.2 or – as soon they
+   will be approved by the European Commission - subsequent
+   versions of the EUPL (the "Licence");
+ * You may not use this work except in compliance with the
+   Licence.
+ * You may obtain a copy of the Licence at:
+ *
+ * https://joinup.ec.europa.eu/software/page/eupl5
+ *
+ * Unless required by applicable law or agreed to in
+   writing, software distributed under the Licence is
+   distributed on an "AS IS" basis,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+   express or implied.
+ * See the Licence for the specific language governing
+   permissions and limitations under the Licence.
+*/
+
+package render
+
+import (
+	"bytes"
+	"testing"
+)
+
+// treeTemplate walks the blocks recursively through Children
+const treeTemplate = `{{define "block"}}{{repeat "  " .Depth}}{{.CIDR}} {{.Status}}{{if not .Free}} {{.Allocation.Ident}}{{end}} {{.Addresses}} in {{.Parent}}
+{{range .Children}}{{template "block" .}}{{end}}{{end}}{{.Name}} {{.Superblock}} {{len .Blocks}} blocks
+{{range .TopLevel}}{{template "block" .}}{{end}}`
+
+func TestRenderPoolToTemplate(t *testing.T) {
+	tests := []struct {
+		includeFree bool
+		expected    string
+	}{
+		{true, `test 10.42.0.0/22 7 blocks
+10.42.0.0/24 allocated first 256 in 10.42.0.0/22
+  10.42.0.0/26 reserved sub 64 in 10.42.0.0/24
+  10.42.0.64/26 free 64 in 10.42.0.0/24
+  10.42.0.128/25 free 128 in 10.42.0.0/24
+10.42.1.0/24 free 256 in 10.42.0.0/22
+10.42.2.0/24 allocated second 256 in 10.42.0.0/22
+10.42.3.0/24 free 256 in 10.42.0.0/22
+`},
+		{false, `test 10.42.0.0/22 3 blocks
+10.42.0.0/24 allocated first 256 in 10.42.0.0/22
+  10.42.0.0/26 reserved sub 64 in 10.42.0.0/24
+10.42.2.0/24 allocated second 256 in 10.42.0.0/22
+`},
+	}
+	parsed := parseFixture(t, fixtureText)
+	for _, tt := range tests {
+		out := &bytes.Buffer{}
+		if err := RenderPoolToTemplate(out, parsed, "tree", treeTemplate, tt.includeFree); err != nil {
+			t.Fatal(err)
+		}
+		expectOutput(t, out, tt.expected)
+	}
+
+	if err := RenderPoolToTemplate(&bytes.Buffer{}, parsed, "broken", "{{.Nope", false); err == nil {
+		t.Error("expected an error for a broken template")
+	}
+	if err := RenderPoolToTemplate(&bytes.Buffer{}, parsed, "missing", "{{.Nope}}", false); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+}